@@ -0,0 +1,101 @@
+package app
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"tgj-bot/models"
+	"tgj-bot/templates"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+type callbackAction string
+
+const (
+	takeAction     = callbackAction("take")
+	reassignAction = callbackAction("reassign")
+	snoozeAction   = callbackAction("snooze")
+)
+
+// one day in seconds
+const snoozeDelay = 24 * 60 * 60
+
+func (a *App) reviewKeyboard(mr models.MR) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(a.text(templates.ButtonTake, nil), callbackData(takeAction, mr.ID)),
+			tgbotapi.NewInlineKeyboardButtonData(a.text(templates.ButtonReassign, nil), callbackData(reassignAction, mr.ID)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(a.text(templates.ButtonSnooze, nil), callbackData(snoozeAction, mr.ID)),
+			tgbotapi.NewInlineKeyboardButtonURL(a.text(templates.ButtonOpen, nil), mr.URL),
+		),
+	)
+}
+
+func callbackData(action callbackAction, mrID int) string {
+	return fmt.Sprintf("%s:%d", action, mrID)
+}
+
+func parseCallbackData(data string) (action callbackAction, mrID int, err error) {
+	parts := strings.SplitN(data, ":", 2)
+	if len(parts) != 2 {
+		return "", 0, fmt.Errorf("invalid callback data: %s", data)
+	}
+	mrID, err = strconv.Atoi(parts[1])
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid callback data: %s", data)
+	}
+	return callbackAction(parts[0]), mrID, nil
+}
+
+func (a *App) callbackHandler(query *tgbotapi.CallbackQuery) (err error) {
+	action, mrID, err := parseCallbackData(query.Data)
+	if err != nil {
+		return
+	}
+
+	u, err := a.DB.GetUserByTgID(strconv.Itoa(query.From.ID))
+	if err != nil {
+		return errNotRegistered
+	}
+	// only assigned reviewer can manage the review
+	r, err := a.DB.GetReview(mrID, u.ID)
+	if err != nil {
+		return errNotReviewer
+	}
+	if r.IsApproved {
+		return errReviewApproved
+	}
+
+	answer := a.text(templates.Success, nil)
+	now := time.Now().Unix()
+	switch action {
+	case takeAction:
+		r.IsTaken = true
+		r.UpdatedAt = now
+		err = a.DB.UpdateReviewTaken(r)
+		answer = a.text(templates.AnswerTake, nil)
+	case snoozeAction:
+		// shift reminder for the reviewer at least one day ahead
+		snoozed := now + snoozeDelay - a.Config.Notifier.Delay
+		if snoozed > r.UpdatedAt {
+			r.UpdatedAt = snoozed
+			err = a.DB.UpdateReviewTime(r)
+		}
+		answer = a.text(templates.AnswerSnooze, nil)
+	case reassignAction:
+		err = a.reallocateReview(u, mrID, reasonButton)
+	default:
+		err = fmt.Errorf("unknown action: %s", action)
+	}
+	if err != nil {
+		return
+	}
+
+	a.Telegram.AnswerCallback(query.ID, answer)
+	return
+}
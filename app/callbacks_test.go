@@ -0,0 +1,29 @@
+package app
+
+import "testing"
+
+func TestParseCallbackData(t *testing.T) {
+	tests := []struct {
+		data      string
+		expAction callbackAction
+		expMrID   int
+		isErr     bool
+	}{
+		{callbackData(takeAction, 12), takeAction, 12, false},
+		{callbackData(snoozeAction, 1), snoozeAction, 1, false},
+		{"reassign:42", reassignAction, 42, false},
+		{"reassign", "", 0, true},
+		{"reassign:foo", "", 0, true},
+		{"", "", 0, true},
+	}
+
+	for index, item := range tests {
+		action, mrID, err := parseCallbackData(item.data)
+		if (err != nil) != item.isErr {
+			t.Fatalf("failed at index %d: unexpected err %v", index, err)
+		}
+		if action != item.expAction || mrID != item.expMrID {
+			t.Fatalf("failed at index %d", index)
+		}
+	}
+}
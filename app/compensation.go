@@ -0,0 +1,29 @@
+package app
+
+import (
+	"log"
+
+	ce "tgj-bot/custom_errors"
+)
+
+// compensations undo side effects in gitlab made inside of database transaction,
+// they are run if the transaction is rolled back
+type compensations []compensation
+
+type compensation struct {
+	name string
+	undo func() error
+}
+
+func (c *compensations) add(name string, undo func() error) {
+	*c = append(*c, compensation{name: name, undo: undo})
+}
+
+// run undoes side effects in reverse order, errors are only logged
+func (c compensations) run() {
+	for i := len(c) - 1; i >= 0; i-- {
+		if err := c[i].undo(); err != nil {
+			log.Println(ce.Wrap(err, "compensate "+c[i].name))
+		}
+	}
+}
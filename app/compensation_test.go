@@ -0,0 +1,28 @@
+package app
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCompensations_Run(t *testing.T) {
+	var order []string
+	undo := func(name string, err error) func() error {
+		return func() error {
+			order = append(order, name)
+			return err
+		}
+	}
+
+	var c compensations
+	c.add("gitlab", undo("gitlab", nil))
+	c.add("telegram", undo("telegram", errors.New("message not found")))
+	c.add("labels", undo("labels", nil))
+	c.run()
+
+	// failed compensation does not stop the rest
+	if expected := []string{"labels", "telegram", "gitlab"}; !reflect.DeepEqual(order, expected) {
+		t.Fatalf("compensations run in wrong order: %v", order)
+	}
+}
@@ -0,0 +1,54 @@
+package app
+
+import (
+	ce "tgj-bot/custom_errors"
+	tg "tgj-bot/external_service/telegram"
+	"tgj-bot/templates"
+)
+
+// userError is shown to the user in language of the chat, text is rendered from the template by name
+type userError struct {
+	name string
+	// string argument of the template, e.g. username
+	arg string
+}
+
+func newUserError(name, arg string) error {
+	return &userError{name: name, arg: arg}
+}
+
+func (e *userError) Error() string {
+	if e.arg == "" {
+		return e.name
+	}
+	return e.name + ": " + e.arg
+}
+
+var (
+	errNotRegistered      = newUserError(templates.ErrNotRegistered, "")
+	errNotReviewer        = newUserError(templates.ErrNotReviewer, "")
+	errReviewApproved     = newUserError(templates.ErrReviewApproved, "")
+	errMrTitle            = newUserError(templates.ErrMrTitle, "")
+	errGetUsers           = newUserError(templates.ErrGetUsers, "")
+	errRegisterUsage      = newUserError(templates.ErrRegisterUsage, "")
+	errMrUsage            = newUserError(templates.ErrMrUsage, "")
+	errFeatureUnavailable = newUserError(templates.ErrFeatureUnavailable, "")
+)
+
+// errors of other packages which are shown to users
+var sharedErrors = map[error]string{
+	ce.ErrUserNorRegistered:      templates.ErrNotRegistered,
+	ce.ErrNotReviewer:            templates.ErrNotReviewer,
+	ce.ErrUsersForReviewNotFound: templates.ErrNoReviewers,
+}
+
+// errorText renders error for the chat, unknown errors are shown as is
+func (a *App) errorText(chatID int64, err error) string {
+	if e, ok := err.(*userError); ok {
+		return a.textFor(chatID, e.name, e.arg)
+	}
+	if name, ok := sharedErrors[err]; ok {
+		return a.textFor(chatID, name, "")
+	}
+	return tg.Escape(err.Error())
+}
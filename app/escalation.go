@@ -0,0 +1,186 @@
+package app
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	ce "tgj-bot/custom_errors"
+	"tgj-bot/external_service/jira"
+	"tgj-bot/models"
+	"tgj-bot/templates"
+)
+
+type EscalationConfig struct {
+	IsAllow bool           `json:"is_allow"`
+	Default EscalationRule `json:"default"`
+	// rules by jira priority: highest, high, medium, low, lowest
+	Priority map[string]EscalationRule `json:"priority"`
+}
+
+// EscalationRule sets thresholds in business hours (weekends are skipped), zero disables the step
+type EscalationRule struct {
+	ReminderHours   float64 `json:"reminder"`
+	LeadHours       float64 `json:"lead"`
+	ReallocateHours float64 `json:"reallocate"`
+}
+
+var priorityNames = map[int]string{
+	jira.PriorityHighest: "highest",
+	jira.PriorityHigh:    "high",
+	jira.PriorityMedium:  "medium",
+	jira.PriorityLow:     "low",
+	jira.PriorityLowest:  "lowest",
+}
+
+func (c EscalationConfig) rule(priority int) EscalationRule {
+	if r, ok := c.Priority[priorityNames[priority]]; ok {
+		return r
+	}
+	return c.Default
+}
+
+// step returns escalation step which is due after stalled hours
+func (r EscalationRule) step(hours float64) models.EscalationStep {
+	switch {
+	case r.ReallocateHours > 0 && hours >= r.ReallocateHours:
+		return models.EscalationReallocate
+	case r.LeadHours > 0 && hours >= r.LeadHours:
+		return models.EscalationLead
+	case r.ReminderHours > 0 && hours >= r.ReminderHours:
+		return models.EscalationReminder
+	}
+	return models.EscalationNone
+}
+
+func (a *App) escalateReviews() {
+	if !a.Config.Escalation.IsAllow {
+		log.Println("Escalations does not allow in config")
+		return
+	}
+
+	lock := a.DB.NewLock(jobEscalation)
+	go func() {
+		for range time.Tick(a.Config.Timings.checkEscalationPeriod()) {
+			if !a.isLeader(lock) {
+				continue
+			}
+			log.Println("escalate stalled reviews...")
+			if err := a.escalateStalledReviews(time.Now()); err != nil {
+				a.logError(err)
+			}
+		}
+	}()
+}
+
+func (a *App) escalateStalledReviews(now time.Time) error {
+	rs, err := a.DB.GetStalledReviews()
+	if err != nil {
+		return err
+	}
+
+	// continue on error in the hope of the best
+	for _, r := range rs {
+		hours := businessHours(time.Unix(r.UpdatedAt, 0), now)
+		if a.isReminderPaused(r.PipelineStatus) {
+			continue
+		}
+		step := a.Config.Escalation.rule(r.JiraPriority).step(hours)
+		// review taken in work is not moved away from the reviewer, lead is asked instead
+		if step == models.EscalationReallocate && r.IsTaken {
+			step = models.EscalationLead
+		}
+		if step <= r.Step {
+			continue
+		}
+
+		// record step before announce, so it is never repeated
+		if err := a.DB.SaveEscalation(r.Review, step); err != nil {
+			log.Println(ce.Wrap(err, "escalate review"))
+			continue
+		}
+		if err := a.escalateReview(r.Review, step, hours); err != nil {
+			log.Println(ce.Wrap(err, fmt.Sprintf("escalate review mr_id=%d user_id=%d step=%d", r.MrID, r.UserID, step)))
+		}
+	}
+	return nil
+}
+
+func (a *App) escalateReview(r models.Review, step models.EscalationStep, hours float64) error {
+	u, err := a.DB.GetUserByID(r.UserID)
+	if err != nil {
+		return err
+	}
+	mr, err := a.DB.GetMrByID(r.MrID)
+	if err != nil {
+		return err
+	}
+
+	data := escalationData{
+		Mention: mention(u.UserBrief),
+		Hours:   int(hours),
+		URL:     mr.URL,
+		Title:   mrTitle(mr),
+	}
+	dedupKey := fmt.Sprintf("escalation:%d:%d:%d:%d", r.MrID, r.UserID, step, r.UpdatedAt)
+	switch step {
+	case models.EscalationReminder:
+		a.sendMessageOnce(dedupKey, a.text(templates.EscalationReminder, data))
+	case models.EscalationLead:
+		leads, err := a.getEscalationLeads(u, r.MrID)
+		if err != nil {
+			return err
+		}
+		for _, l := range leads {
+			data.Leads = append(data.Leads, mention(l))
+		}
+		a.sendMessageOnce(dedupKey, a.text(templates.EscalationLead, data))
+	case models.EscalationReallocate:
+		a.sendMessageOnce(dedupKey, a.text(templates.EscalationReallocate, data))
+		return a.reallocateReview(u, r.MrID, reasonEscalation)
+	}
+	return nil
+}
+
+// getEscalationLeads returns mr's lead reviewers or all active leads if there are none
+func (a *App) getEscalationLeads(u models.User, mrID int) (leads []models.UserBrief, err error) {
+	reviewers, err := a.DB.GetUsersByMrID(mrID)
+	if err != nil {
+		return
+	}
+	for _, r := range reviewers {
+		if r.Role == models.Lead && r.ID != u.ID {
+			leads = append(leads, r)
+		}
+	}
+	if len(leads) > 0 {
+		return
+	}
+
+	us, err := a.DB.GetActiveUsers()
+	if err != nil {
+		return
+	}
+	for _, l := range us {
+		if l.Role == models.Lead && l.ID != u.ID {
+			leads = append(leads, l.UserBrief)
+		}
+	}
+	return
+}
+
+// businessHours returns hours between two moments skipping weekends
+func businessHours(from, to time.Time) float64 {
+	var d time.Duration
+	for from.Before(to) {
+		next := time.Date(from.Year(), from.Month(), from.Day()+1, 0, 0, 0, 0, from.Location())
+		if next.After(to) {
+			next = to
+		}
+		if wd := from.Weekday(); wd != time.Saturday && wd != time.Sunday {
+			d += next.Sub(from)
+		}
+		from = next
+	}
+	return d.Hours()
+}
@@ -0,0 +1,86 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"tgj-bot/external_service/jira"
+	"tgj-bot/models"
+)
+
+func TestBusinessHours(t *testing.T) {
+	// 2019-10-04 is friday
+	friday := time.Date(2019, 10, 4, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		from time.Time
+		to   time.Time
+		exp  float64
+	}{
+		{friday, friday.Add(6 * time.Hour), 6},
+		// weekend is skipped
+		{friday, friday.Add(3 * 24 * time.Hour), 24},
+		{friday.Add(24 * time.Hour), friday.Add(48 * time.Hour), 0},
+		{friday.Add(24 * time.Hour), friday.Add(4 * 24 * time.Hour), 36},
+		{friday, friday.Add(-time.Hour), 0},
+	}
+
+	for index, item := range tests {
+		if value := businessHours(item.from, item.to); value != item.exp {
+			t.Fatalf("failed at index %d: %v", index, value)
+		}
+	}
+}
+
+func TestEscalationRule_Step(t *testing.T) {
+	cfg := EscalationConfig{
+		Default: EscalationRule{ReminderHours: 24, LeadHours: 48, ReallocateHours: 72},
+		Priority: map[string]EscalationRule{
+			"highest": {ReminderHours: 2, LeadHours: 4},
+		},
+	}
+	tests := []struct {
+		priority int
+		hours    float64
+		exp      models.EscalationStep
+	}{
+		{jira.PriorityMedium, 1, models.EscalationNone},
+		{jira.PriorityMedium, 24, models.EscalationReminder},
+		{jira.PriorityMedium, 50, models.EscalationLead},
+		{jira.PriorityUndefined, 100, models.EscalationReallocate},
+		{jira.PriorityHighest, 3, models.EscalationReminder},
+		// reallocation is disabled for the priority
+		{jira.PriorityHighest, 100, models.EscalationLead},
+	}
+
+	for index, item := range tests {
+		if value := cfg.rule(item.priority).step(item.hours); value != item.exp {
+			t.Fatalf("failed at index %d: %v", index, value)
+		}
+	}
+}
+
+func TestSkipWeekends(t *testing.T) {
+	a := App{Config: Config{Escalation: EscalationConfig{Default: EscalationRule{ReminderHours: 24, LeadHours: 48, ReallocateHours: 72}}}}
+	// 2019-10-04 is friday
+	friday := time.Date(2019, 10, 4, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		now time.Time
+		exp time.Time
+	}{
+		{friday, friday},
+		{friday.Add(24 * time.Hour), friday.Add(3 * 24 * time.Hour)},
+		{friday.Add(48 * time.Hour), friday.Add(3 * 24 * time.Hour)},
+	}
+
+	for index, item := range tests {
+		updatedAt := a.skipWeekends(item.now.Unix())
+		if updatedAt != item.exp.Unix() {
+			t.Fatalf("failed at index %d: %v", index, time.Unix(updatedAt, 0))
+		}
+		// review with threads resolved just now is not escalated on the next tick
+		hours := businessHours(time.Unix(updatedAt, 0), item.now.Add(time.Minute))
+		if step := a.Config.Escalation.rule(jira.PriorityMedium).step(hours); step != models.EscalationNone {
+			t.Fatalf("failed at index %d: escalated to %v", index, step)
+		}
+	}
+}
@@ -2,7 +2,6 @@ package app
 
 import (
 	"database/sql"
-	"errors"
 	"fmt"
 	"log"
 	"regexp"
@@ -13,22 +12,19 @@ import (
 	"tgj-bot/models"
 
 	ce "tgj-bot/custom_errors"
+	db "tgj-bot/external_service/database"
+	gl "tgj-bot/external_service/gitlab"
+	"tgj-bot/templates"
 
 	"github.com/go-telegram-bot-api/telegram-bot-api"
 )
 
 var titleMRRegexp = regexp.MustCompile(`NC-\d+`)
 
-func (a *App) helpHandler() error {
-	a.Telegram.SendMessage(fmt.Sprint("/register gitlab_id [role=dev]\n" + "/mr merge_request_url\n" +
-		"/inactive [username]\n" + "/active [username]\n"))
-	return nil
-}
-
 func (a *App) registerHandler(update tgbotapi.Update) (err error) {
 	argsStr := update.Message.CommandArguments()
 	if argsStr == "" {
-		err = errors.New("command require two arguments. For more information use /help")
+		err = errRegisterUsage
 		return
 	}
 	args := strings.Split(strings.ToLower(argsStr), " ")
@@ -37,7 +33,6 @@ func (a *App) registerHandler(update tgbotapi.Update) (err error) {
 		UserBrief: models.UserBrief{
 			TelegramID:       strconv.Itoa(update.Message.From.ID),
 			TelegramUsername: strings.ToLower(update.Message.From.UserName),
-			Role:             models.Developer,
 		},
 		JiraID:   "",
 		IsActive: true,
@@ -47,7 +42,7 @@ func (a *App) registerHandler(update tgbotapi.Update) (err error) {
 		if models.IsValidRole(role) {
 			user.Role = role
 		} else {
-			return errors.New(fmt.Sprintf("second parameter (role) must be equal one of %v", models.ValidRoles))
+			return newUserError(templates.ErrInvalidRole, fmt.Sprint(models.ValidRoles))
 		}
 	}
 
@@ -56,61 +51,66 @@ func (a *App) registerHandler(update tgbotapi.Update) (err error) {
 		return ce.WrapWithLog(err, fmt.Sprintf("get gitlab info %v", args[0]))
 	}
 
+	if a.Config.Registration.IsVerify {
+		return a.requestVerification(user)
+	}
 	if _, err = a.DB.SaveUser(user); err != nil {
 		return err
 	}
-	a.Telegram.SendMessage(success)
+	a.sendMessage(a.text(templates.Success, nil))
 	return
 }
 
 func (a *App) isActiveHandler(update tgbotapi.Update, isActive bool) (err error) {
-	argsStr := update.Message.CommandArguments()
-	var telegramUsername string
-	if argsStr == "" {
-		telegramUsername = update.Message.From.UserName
-	} else {
-		args := strings.Split(strings.ToLower(argsStr), " ")
-		telegramUsername = args[0]
-	}
-
-	u, err := a.DB.GetUserByTgUsername(telegramUsername)
+	u, err := a.targetUser(update)
 	if err != nil {
 		return
 	}
 	if u.IsActive == isActive {
 		// nothing to update
-		a.Telegram.SendMessage(success)
+		a.sendMessage(a.text(templates.Success, nil))
 		return
 	}
 
-	if err = a.DB.ChangeIsActiveUser(telegramUsername, isActive); err != nil {
+	if err = a.DB.ChangeIsActiveUser(u.ID, isActive); err != nil {
 		return
 	}
 
 	// reallocate MRs for inactive user
 	if !isActive {
-		if err = a.reallocateUserMRs(u); err != nil {
-			return
+		failed, err := a.reallocateUserMRs(u, reasonInactive)
+		if err != nil {
+			return err
+		}
+		if len(failed) > 0 {
+			return newUserError(templates.ErrReviewsNotMoved, strings.Join(failed, ", "))
 		}
 	}
-	a.Telegram.SendMessage(success)
+	a.sendMessage(a.text(templates.Success, nil))
 	return
 }
 
 func (a *App) mrHandler(update tgbotapi.Update) (err error) {
 	argsStr := update.Message.CommandArguments()
 	if argsStr == "" {
-		err = errors.New("command require one argument. For more information use /help")
+		err = errMrUsage
 		return
 	}
-	args := strings.Split(strings.ToLower(argsStr), " ")
+	args := strings.Fields(strings.ToLower(argsStr))
 
 	mrUrl := args[0]
 	mrGitlabID, err := models.GetGitlabID(mrUrl)
 	if err != nil {
 		return
 	}
+	overrides, err := parseReviewOverrides(args[1:])
+	if err != nil {
+		return
+	}
 
+	if pending, err := a.DB.GetMrByGitlabID(mrGitlabID); err == nil && pending.IsPending {
+		return a.checkPendingMR(pending)
+	}
 	if a.isMrAlreadyExist(mrGitlabID) {
 		return a.returnMrParty(mrGitlabID)
 	}
@@ -120,28 +120,115 @@ func (a *App) mrHandler(update tgbotapi.Update) (err error) {
 		return
 	}
 	if !isMrTitleValid(gitlabMR.Title) {
-		return errors.New("mr title must have ticket number in square brackets without spaces inside. Example:[NC-1234]")
+		return errMrTitle
 	}
-	author, err := a.DB.GetUserByGitlabID(gitlabMR.AuthorID)
+	author, err := a.mrAuthor(gitlabMR.AuthorID)
 	if err != nil {
-		if err != sql.ErrNoRows {
+		return
+	}
+
+	mr := models.MR{
+		URL:      mrUrl,
+		AuthorID: &author.ID,
+		GitlabID: mrGitlabID,
+		Title:    gitlabMR.Title,
+		HeadSHA:  gitlabMR.SHA,
+
+		PipelineStatus: gitlabMR.PipelineStatus,
+	}
+	// reviewers are assigned when mr leaves draft and pipeline passes
+	if !a.isReadyForReview(gitlabMR) {
+		mr.IsPending = true
+		mr.PendingArgs = strings.Join(args[1:], " ")
+		if _, err = a.DB.CreateMR(mr); err != nil {
 			return
 		}
-		author = models.User{UserBrief: models.UserBrief{ID: 0}}
+		a.sendPendingReason(mr, gitlabMR)
+		return
 	}
 
 	if err = a.updateReviews(); err != nil {
 		return
 	}
+	return a.assignReviewers(mr, author, overrides)
+}
+
+// mrAuthor returns registered author of mr, empty user if author is not registered
+func (a *App) mrAuthor(gitlabID int) (models.User, error) {
+	author, err := a.DB.GetUserByGitlabID(gitlabID)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			return author, err
+		}
+		author = models.User{UserBrief: models.UserBrief{ID: 0}}
+	}
+	return author, nil
+}
+
+// checkPendingMR assigns reviewers if pending mr became ready, otherwise reminds that it is deferred
+func (a *App) checkPendingMR(mr models.MR) error {
+	gitlabMR, err := a.Gitlab.GetMrByID(mr.GitlabID)
+	if err != nil {
+		return err
+	}
+	if !a.isReadyForReview(gitlabMR) {
+		a.sendPendingReason(mr, gitlabMR)
+		return nil
+	}
+	return a.assignPendingMR(mr, gitlabMR)
+}
+
+// sendPendingReason explains why reviewers are not assigned yet
+func (a *App) sendPendingReason(mr models.MR, gitlabMR *gl.GitlabMR) {
+	name := templates.DraftPending
+	if !gitlabMR.IsDraft {
+		name = templates.PipelinePending
+	}
+	a.sendMessage(a.text(name, mrStatusData{URL: mr.URL, Title: mrTitle(mr)}))
+}
+
+// assignPendingMR assigns reviewers to mr which became ready, with /mr arguments saved on deferring
+func (a *App) assignPendingMR(mr models.MR, gitlabMR *gl.GitlabMR) error {
+	overrides, err := parseReviewOverrides(strings.Fields(mr.PendingArgs))
+	if err != nil {
+		return err
+	}
+	author, err := a.mrAuthor(gitlabMR.AuthorID)
+	if err != nil {
+		return err
+	}
+	mr.Title = gitlabMR.Title
+	log.Printf("mr_id=%d left draft, assigning reviewers", mr.ID)
+	return a.assignReviewers(mr, author, overrides)
+}
 
+// assignReviewers picks review party and posts live status message of mr,
+// new mr is saved only when reviewers are found
+func (a *App) assignReviewers(mr models.MR, author models.User, overrides reviewOverrides) error {
+	// payload of reviewers is read and increased at once, mrs assigned concurrently by any replica do not pick the same reviewer
+	err := a.DB.WithLock(lockAssign, func() error {
+		return a.saveReviewers(mr, author, overrides)
+	})
+	if err != nil {
+		return err
+	}
+	a.flush(a.Config.Tg.ChatID)
+	return nil
+}
+
+// saveReviewers picks review party, saves reviews with status message to the outbox and writes reviewers to gitlab
+func (a *App) saveReviewers(mr models.MR, author models.User, overrides reviewOverrides) (err error) {
 	users, err := a.DB.GetUsersWithPayload(author.TelegramID)
 	if err != nil {
 		log.Printf("getting users failed: %v", err)
-		return errors.New("getting users failed")
+		return errGetUsers
 	}
 
+	if err = a.checkForcedReviewers(users, overrides.forced, author); err != nil {
+		return
+	}
 	// if the party is not picked up, but not zero, then everything is OK
-	reviewParty, err := getParticipants(users, a.Config.Rp)
+	reviewParty, err := getParticipants(users, a.Config.Rp, overrides)
 	if err != nil {
 		return
 	}
@@ -149,103 +236,141 @@ func (a *App) mrHandler(update tgbotapi.Update) (err error) {
 		return ce.ErrUsersForReviewNotFound
 	}
 
-	mr := models.MR{
-		URL:      mrUrl,
-		AuthorID: &author.ID,
-		GitlabID: mrGitlabID,
-	}
-	mr, err = a.DB.CreateMR(mr)
-	if err != nil {
+	if err = a.resolveGitlabUsers(reviewParty); err != nil {
 		return
 	}
-
-	review := models.Review{
-		MrID:      mr.ID,
-		UpdatedAt: time.Now().Unix(),
-	}
-
-	msg := "New merge request " + randJoyEmoji() + "\n"
 	reviewPartyBrief := make([]models.UserBrief, 0, len(reviewParty))
-	for i := range reviewParty {
+	for _, u := range reviewParty {
+		reviewPartyBrief = append(reviewPartyBrief, u.UserBrief)
+	}
 
-		// todo remove in next version
-		if reviewParty[i].GitlabName == "" {
-			reviewParty[i].GitlabName, err = a.Gitlab.GetUserByID(reviewParty[i].GitlabID)
-			if err != nil {
-				return ce.WrapWithLog(err, fmt.Sprintf("MR handler fail to get gitlab name for %v", reviewParty[i].TelegramUsername))
-			}
-			if _, err = a.DB.SaveUser(models.User{UserBrief: reviewParty[i].UserBrief}); err != nil {
-				return ce.WrapWithLog(err, "MR handler fail")
+	// mr, reviews and status message are saved together, gitlab is updated the last and undone if commit fails
+	var undo compensations
+	err = a.DB.WithTx(func(tx *db.Client) (err error) {
+		if mr.ID == 0 {
+			if mr, err = tx.CreateMR(mr); err != nil {
+				return
 			}
 		}
-
-		// todo remove in next version
-		if reviewParty[i].GitlabID == 0 {
-			reviewParty[i].GitlabID, err = a.Gitlab.GetUserByName(reviewParty[i].GitlabName)
-			if err != nil {
-				return ce.WrapWithLog(err, fmt.Sprintf("MR handler fail to get gitlab id for %v", reviewParty[i].TelegramUsername))
-			}
-			if _, err = a.DB.SaveUser(models.User{UserBrief: reviewParty[i].UserBrief}); err != nil {
-				return ce.WrapWithLog(err, "MR handler fail")
+		review := models.Review{
+			MrID:      mr.ID,
+			UpdatedAt: time.Now().Unix(),
+		}
+		for _, u := range reviewPartyBrief {
+			review.UserID = u.ID
+			if err = tx.SaveReview(review); err != nil {
+				return
 			}
 		}
-
-		reviewPartyBrief = append(reviewPartyBrief, reviewParty[i].UserBrief)
-		review.UserID = reviewParty[i].ID
-		if err = a.DB.SaveReview(review); err != nil {
+		reviewers, err := tx.GetReviewersByMrID(mr.ID)
+		if err != nil {
+			return
+		}
+		// status message is sent only if mr is saved, it is linked to mr once delivered
+		_, err = a.enqueueTo(tx, a.Config.Tg.ChatID, statusDedupKey(mr.ID), a.renderMrStatus(mr, models.StateOpened, reviewers), a.reviewKeyboard(mr), time.Time{})
+		if err != nil {
+			return
+		}
+		mr.IsPending = false
+		if _, err = tx.SaveMR(mr); err != nil {
 			return
 		}
-		msg += fmt.Sprintf("%s @%v\n", pointEmoji[i%2], reviewParty[i].TelegramUsername)
-	}
-
-	msg += cutoff + "\n" + mrUrl
 
-	if err = a.Gitlab.WriteReviewers(mr.GitlabID, reviewPartyBrief); err != nil {
-		log.Println(err)
+		if err = a.Gitlab.WriteReviewers(mr.GitlabID, reviewPartyBrief); err != nil {
+			log.Println(err)
+			return
+		}
+		gitlabID := mr.GitlabID
+		undo.add("gitlab reviewers", func() error {
+			return a.Gitlab.ClearReviewers(gitlabID)
+		})
 		return
+	})
+	if err != nil {
+		undo.run()
 	}
-
-	a.Telegram.SendMessage(msg)
 	return
 }
 
+// resolveGitlabUsers fills gitlab name and id of reviewers registered before they were saved
+//
+// todo remove in next version
+func (a *App) resolveGitlabUsers(users models.UsersPayload) (err error) {
+	for i := range users {
+		if users[i].GitlabName != "" && users[i].GitlabID != 0 {
+			continue
+		}
+		if users[i].GitlabName == "" {
+			users[i].GitlabName, err = a.Gitlab.GetUserByID(users[i].GitlabID)
+			if err != nil {
+				return ce.WrapWithLog(err, fmt.Sprintf("MR handler fail to get gitlab name for %v", users[i].TelegramUsername))
+			}
+		}
+		if users[i].GitlabID == 0 {
+			users[i].GitlabID, err = a.Gitlab.GetUserByName(users[i].GitlabName)
+			if err != nil {
+				return ce.WrapWithLog(err, fmt.Sprintf("MR handler fail to get gitlab id for %v", users[i].TelegramUsername))
+			}
+		}
+		if _, err = a.DB.SaveUser(models.User{UserBrief: users[i].UserBrief}); err != nil {
+			return ce.WrapWithLog(err, "MR handler fail")
+		}
+	}
+	return nil
+}
+
 func (a *App) updateReviews() error {
 	//
-	// get all open MRI
+	// get all open MRI and recently closed ones, they may be reopened
 	// 	 go to mr
 	// 	 get likes and comments
 	// 	 update values in the table reviews
-	// close MRs with all approved reviews
+	// mark MRs with all approved reviews
 	//
 	mrs, err := a.DB.GetOpenedMRs()
 	if err != nil {
 		return err
 	}
-	for _, mr := range mrs {
-		mrIsOpen, err := a.Gitlab.MrIsOpen(mr.GitlabID)
-		log.Printf("Update reviews mr_id=%d is_open=%v", mr.ID, mrIsOpen)
-		if !mrIsOpen {
-			_ = ce.WrapWithLog(a.DB.CloseMR(mr.ID), "close mr err")
-		}
-		if err = a.updateMrLikes(mr); err != nil {
-			_ = ce.WrapWithLog(err, "update mr likes")
-		}
-		if err = a.updateMrComments(mr); err != nil {
-			_ = ce.WrapWithLog(err, "update mr comments")
+	abandoned, err := a.DB.GetAbandonedMRs(time.Now().Add(-a.Config.Timings.reopenWindow()).Unix())
+	if err != nil {
+		return err
+	}
+	mrs = append(mrs, abandoned...)
+
+	// mrs are independent, so they are refreshed concurrently, gitlab client limits the rate of requests
+	started := time.Now()
+	loaded := make([]*gl.GitlabMR, len(mrs))
+	forEach(len(mrs), a.refreshWorkers(), func(i int) {
+		loaded[i] = a.refreshMR(mrs[i])
+	})
+	log.Printf("refreshed %d mrs from gitlab in %v", len(mrs), time.Since(started))
+
+	// loaded mrs by id, reused when mr becomes reviewed
+	gitlabMRs := make(map[int]*gl.GitlabMR, len(mrs))
+	for i, gitlabMR := range loaded {
+		if gitlabMR != nil {
+			gitlabMRs[mrs[i].ID] = gitlabMR
 		}
 	}
 
-	closedMRs, err := a.DB.CloseMRs()
+	reviewedMRs, err := a.DB.MarkMRsReviewed()
 	if err != nil {
 		return err
 	}
-	for _, mr := range closedMRs {
+	for _, mr := range reviewedMRs {
 		if err = a.Gitlab.SetLabelToMR(mr.GitlabID, models.ReviewedLabel); err != nil {
 			log.Printf("err set label for mr_id=%d: %v", mr.GitlabID, err)
 			continue
 		}
 		log.Printf("successfully set label for mr_id=%d", mr.GitlabID)
 
+		if err = a.updateMrStatus(mr, models.StateOpened); err != nil {
+			_ = ce.WrapWithLog(err, "update mr status")
+		}
+		if err = a.readyToMerge(mr, gitlabMRs[mr.ID]); err != nil {
+			_ = ce.WrapWithLog(err, "ready to merge")
+		}
+
 		if mr.IsOnReview() {
 			if err := a.notifyReviewTask(mr); err != nil {
 				log.Printf("err notify task mr_id=%d: %v", mr.GitlabID, err)
@@ -257,13 +382,105 @@ func (a *App) updateReviews() error {
 	return nil
 }
 
+// refreshMR updates state, reviews and threads of mr from gitlab, mr is loaded once and returned, nil if it fails.
+// It is called concurrently for different mrs
+func (a *App) refreshMR(mr models.MR) *gl.GitlabMR {
+	var state string
+	var authorID int
+	wasAbandoned := mr.IsAbandoned()
+	gitlabMR, err := a.Gitlab.GetMrByID(mr.GitlabID)
+	if err != nil {
+		_ = ce.WrapWithLog(err, "get mr state")
+	} else {
+		state, authorID = gitlabMR.State, gitlabMR.AuthorID
+		if err = a.updateMrState(&mr, gitlabMR); err != nil {
+			_ = ce.WrapWithLog(err, "update mr state")
+		}
+		if state == models.StateOpened {
+			if err = a.checkNewCommits(mr, gitlabMR.SHA); err != nil {
+				_ = ce.WrapWithLog(err, "check new commits")
+			}
+			if err = a.checkPipeline(mr, gitlabMR); err != nil {
+				_ = ce.WrapWithLog(err, "check pipeline")
+			}
+		}
+	}
+	// still closed
+	if wasAbandoned && mr.IsAbandoned() {
+		return gitlabMR
+	}
+	if mr.IsPending {
+		if gitlabMR != nil && state == models.StateOpened && a.isReadyForReview(gitlabMR) {
+			if err = a.assignPendingMR(mr, gitlabMR); err != nil {
+				_ = ce.WrapWithLog(err, "assign pending mr")
+			}
+		}
+		return gitlabMR
+	}
+	log.Printf("Update reviews mr_id=%d state=%v", mr.ID, state)
+	if err = a.updateMrLikes(mr); err != nil {
+		_ = ce.WrapWithLog(err, "update mr likes")
+	}
+	threads, err := a.updateMrComments(mr, authorID)
+	if err != nil {
+		_ = ce.WrapWithLog(err, "update mr comments")
+	} else if state == models.StateOpened {
+		if err = a.checkStale(mr, gitlabMR, threads); err != nil {
+			_ = ce.WrapWithLog(err, "check stale mr")
+		}
+	}
+	if state != "" {
+		if err = a.updateMrStatus(mr, state); err != nil {
+			_ = ce.WrapWithLog(err, "update mr status")
+		}
+	}
+	return gitlabMR
+}
+
+// updateMrState saves changed gitlab state of mr, reopened mr is tracked again
+func (a *App) updateMrState(mr *models.MR, gitlabMR *gl.GitlabMR) error {
+	if mr.State == gitlabMR.State {
+		return nil
+	}
+	wasClosed := mr.IsClosed
+	mergedAt, closedAt := gitlabMR.MergedAt, gitlabMR.ClosedAt
+	// older gitlab does not report the time
+	now := time.Now().Unix()
+	if gitlabMR.State == models.StateMerged && mergedAt == 0 {
+		mergedAt = now
+	}
+	if gitlabMR.State == models.StateClosed && closedAt == 0 {
+		closedAt = now
+	}
+	mr.SetState(gitlabMR.State, mergedAt, closedAt)
+	if err := a.DB.UpdateMrState(*mr); err != nil {
+		return err
+	}
+	if wasClosed && !mr.IsClosed {
+		log.Printf("mr_id=%d is reopened, reviews are tracked again", mr.ID)
+	}
+	return nil
+}
+
 func (a *App) updateMrLikes(mr models.MR) error {
 	usersID, err := a.Gitlab.CheckMrLikes(mr.GitlabID)
 	if err != nil {
 		return err
 	}
 	log.Printf("Check mr likes user's ids: %v", usersID)
-	for uID := range usersID {
+	reviewers, err := a.DB.GetReviewersByMrID(mr.ID)
+	if err != nil {
+		return err
+	}
+	resetAt := make(map[int]int64, len(reviewers))
+	for _, r := range reviewers {
+		resetAt[r.GitlabID] = r.ResetAt
+	}
+	for uID, awardedAt := range usersID {
+		// emoji was awarded before approval was reset by new commits
+		if awardedAt.Unix() < resetAt[uID] {
+			continue
+		}
 		u, err := a.DB.GetUserByGitlabID(uID)
 		if err != nil {
 			ce.WrapWithLog(err, fmt.Sprintf("user not found by gitlab id=%d", uID))
@@ -287,11 +504,21 @@ func (a *App) updateMrLikes(mr models.MR) error {
 	return nil
 }
 
-func (a *App) updateMrComments(mr models.MR) error {
-	userGitlabIDList, err := a.Gitlab.CheckMrComments(mr.GitlabID)
+// updateMrComments marks reviewers with unresolved threads and saves whose turn is to reply in threads
+func (a *App) updateMrComments(mr models.MR, authorGitlabID int) ([]models.Thread, error) {
+	threads, err := a.Gitlab.MrThreads(mr.GitlabID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	for i := range threads {
+		threads[i].MrID = mr.ID
+		threads[i].AwaitingID = threads[i].Awaiting(authorGitlabID)
+	}
+	if err = a.DB.SaveThreads(mr.ID, threads); err != nil {
+		return nil, err
 	}
+
+	userGitlabIDList := models.CommentedUsers(threads)
 	log.Printf("Check mr comments user's ids: %v", userGitlabIDList)
 	for gitlabID, isCommented := range userGitlabIDList {
 		u, err := a.DB.GetUserByGitlabID(gitlabID)
@@ -314,10 +541,11 @@ func (a *App) updateMrComments(mr models.MR) error {
 			continue
 		}
 	}
-	return nil
+	return threads, nil
 }
 
-func (a *App) reallocateUserMRs(u models.User) (err error) {
+// reallocateUserMRs moves open reviews of the user to other reviewers, returns urls of mrs which still need a reviewer
+func (a *App) reallocateUserMRs(u models.User, reason string) (failed []string, err error) {
 	// get list unapproved user's mrs
 	//     get mr's reviewers list
 	//     generate review candidate list (not mr's author is active, not already review this mr, same role)
@@ -326,46 +554,90 @@ func (a *App) reallocateUserMRs(u models.User) (err error) {
 
 	mrsID, err := a.DB.GetReviewMRsByUserID(u.ID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	log.Printf("Reallocate MRs for %s: %v\n", u.TelegramUsername, mrsID)
 	// continue on error in the hope of the best
 	for _, mrID := range mrsID {
-		user, err := a.DB.GetUserForReallocateMR(u.UserBrief, mrID)
-		if err != nil {
-			log.Println(ce.Wrap(err, "Reallocate MRs"))
-			continue
+		if err := a.reallocateReview(u, mrID, reason); err != nil {
+			log.Println(err)
+			failed = append(failed, a.mrRef(mrID))
 		}
+	}
+	return failed, nil
+}
 
-		if err = a.DB.UpdateReview(models.Review{
-			MrID:      mrID,
-			UserID:    u.ID,
-			UpdatedAt: time.Now().Unix(),
-		}, user.ID); err != nil {
-			log.Println(ce.Wrap(err, "Reallocate MRs UpdateReview"))
-			continue
+// mrRef returns url of mr to refer it in messages, id if mr is not found
+func (a *App) mrRef(mrID int) string {
+	mr, err := a.DB.GetMrByID(mrID)
+	if err != nil {
+		return strconv.Itoa(mrID)
+	}
+	return mr.URL
+}
+
+// reallocateReview moves review to active user with the same role and the least payload
+func (a *App) reallocateReview(u models.User, mrID int, reason string) error {
+	user, err := a.DB.GetUserForReallocateMR(u.UserBrief, mrID)
+	if err != nil {
+		return ce.Wrap(err, "Reallocate MRs")
+	}
+	return a.moveReview(models.ReviewChange{MrID: mrID, FromUserID: u.ID, ToUserID: user.ID, Reason: reason}, user.UserBrief)
+}
+
+// moveReview hands review over to another user, gitlab reviewers and chat status are updated.
+// Review is moved in database only if gitlab is updated, gitlab is restored if transaction fails
+func (a *App) moveReview(rc models.ReviewChange, to models.UserBrief) error {
+	rc.CreatedAt = time.Now().Unix()
+	mr, err := a.DB.GetMrByID(rc.MrID)
+	if err != nil {
+		return ce.Wrap(err, "Reallocate MRs GetMrByID")
+	}
+	previous, err := a.DB.GetUsersByMrID(rc.MrID)
+	if err != nil {
+		return ce.Wrap(err, "Reallocate MRs GetUsersByMrID")
+	}
+
+	var undo compensations
+	err = a.DB.WithTx(func(tx *db.Client) error {
+		if err := tx.UpdateReview(models.Review{
+			MrID:      rc.MrID,
+			UserID:    rc.FromUserID,
+			UpdatedAt: rc.CreatedAt,
+		}, rc.ToUserID); err != nil {
+			return ce.Wrap(err, "Reallocate MRs UpdateReview")
 		}
-		mr, err := a.DB.GetMrByID(mrID)
-		if err != nil {
-			log.Println(ce.Wrap(err, "Reallocate MRs GetMrByID"))
-			continue
+		if err := tx.SaveReviewChange(rc); err != nil {
+			return ce.Wrap(err, "Reallocate MRs SaveReviewChange")
 		}
-		reviewers, err := a.DB.GetUsersByMrID(mrID)
+		reviewers, err := tx.GetUsersByMrID(rc.MrID)
 		if err != nil {
-			log.Println(ce.Wrap(err, "Reallocate MRs GetUsersByMrID"))
-			continue
+			return ce.Wrap(err, "Reallocate MRs GetUsersByMrID")
 		}
-		err = a.Gitlab.WriteReviewers(mr.GitlabID, reviewers)
-		if err != nil {
-			log.Println(ce.Wrap(err, "Reallocate MRs WriteReviewers"))
-			continue
+		if err = a.Gitlab.WriteReviewers(mr.GitlabID, reviewers); err != nil {
+			return ce.Wrap(err, "Reallocate MRs WriteReviewers")
 		}
-		a.Telegram.SendMessage(fmt.Sprintf("New review:\n@%s\n%s\n%v", user.TelegramUsername, cutoff, mr.URL))
+		undo.add("gitlab reviewers", func() error {
+			return a.Gitlab.WriteReviewers(mr.GitlabID, previous)
+		})
+		return nil
+	})
+	if err != nil {
+		undo.run()
+		return err
+	}
+
+	if err = a.updateMrStatus(mr, models.StateOpened); err != nil {
+		log.Println(ce.Wrap(err, "Reallocate MRs updateMrStatus"))
+	}
+	msg := a.text(templates.NewReview, userMrData{Mention: mention(to), URL: mr.URL, Title: mrTitle(mr)})
+	if err = a.send(a.Config.Tg.ChatID, "", msg, a.reviewKeyboard(mr)); err != nil {
+		log.Println(ce.Wrap(err, "Reallocate MRs send"))
 	}
 	return nil
 }
 
-func (a *App) skipWeekends(endTime int64) (newEndTime int64) {
+func (a *App) skipWeekends(endTime int64) int64 {
 	// +1 day -> monday
 	if time.Unix(endTime+a.Config.Notifier.Delay, 0).Weekday() == time.Sunday {
 		endTime += time.Unix(24*60*60, 0).Unix()
@@ -375,7 +647,7 @@ func (a *App) skipWeekends(endTime int64) (newEndTime int64) {
 	if time.Unix(endTime+a.Config.Notifier.Delay, 0).Weekday() == time.Saturday {
 		endTime += time.Unix(2*24*60*60, 0).Unix()
 	}
-	return
+	return endTime
 }
 
 func (a *App) isMrAlreadyExist(mrID int) bool {
@@ -390,19 +662,31 @@ func (a *App) isMrAlreadyExist(mrID int) bool {
 	return false
 }
 
+func (a *App) statsHandler() error {
+	ups, err := a.DB.GetUsersWithPayload("")
+	if err != nil {
+		return err
+	}
+	data := tableData{Rows: [][]string{{"user", "role", "reviews"}}}
+	for _, u := range ups {
+		data.Rows = append(data.Rows, []string{displayName(u.UserBrief), string(u.Role), strconv.Itoa(u.Payload)})
+	}
+	a.sendMessage(a.text(templates.Stats, data))
+	return nil
+}
+
 func (a *App) returnMrParty(mrID int) (err error) {
 	us, err := a.DB.GetUsersByMrID(mrID)
-	msg := "Review party:\n"
+	data := mrStatusData{URL: a.createMrURL(mrID)}
 	for i, u := range us {
-		msg += fmt.Sprintf("%s %s\n", pointEmoji[i%2], u.TelegramUsername)
+		data.Reviewers = append(data.Reviewers, reviewerLine{Emoji: pointEmoji[i%2], Username: displayName(u), Mention: mention(u)})
 	}
-	msg += cutoff + fmt.Sprintf("\n%s", a.createMrURL(mrID))
-	a.Telegram.SendMessage(msg)
+	a.sendMessage(a.text(templates.MrParty, data))
 	return
 }
 
 func (a *App) getGitlabInfo(arg string) (int, string, error) {
-	invalidArgument := errors.New(fmt.Sprintf("invalid argument %v", arg))
+	invalidArgument := newUserError(templates.ErrInvalidArgument, arg)
 
 	id, err := strconv.Atoi(arg)
 	if err != nil {
@@ -423,18 +707,6 @@ func (a *App) createMrURL(mrID int) string {
 	return a.Config.Gl.MRBaseURL + "/" + strconv.Itoa(mrID)
 }
 
-func getParticipants(users models.UsersPayload, cfg ReviewParty) (rp models.UsersPayload, err error) {
-	devs, err := users.GetN(cfg.DevNum, models.Developer)
-	if err != nil {
-		return nil, err
-	}
-	leads, err := users.GetN(cfg.LeadNum, models.Lead)
-	if err != nil {
-		return nil, err
-	}
-	return append(devs, leads...), nil
-}
-
 func isMrTitleValid(title string) bool {
 	indexes := titleMRRegexp.FindIndex([]byte(title))
 	if len(indexes) == 0 {
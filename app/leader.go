@@ -0,0 +1,36 @@
+package app
+
+import (
+	"log"
+	"time"
+
+	ce "tgj-bot/custom_errors"
+	db "tgj-bot/external_service/database"
+)
+
+// background jobs which must run on one replica of the bot
+const (
+	jobNotify      = "notify"
+	jobGitlabState = "gitlab_state"
+	jobJiraTasks   = "jira_tasks"
+	jobEscalation  = "escalation"
+	jobOutbox      = "outbox"
+	// polling of telegram updates
+	jobUpdates = "updates"
+)
+
+// standby replica checks whether it can poll telegram updates, leader checks that it still holds the lock
+const updatesLeaderPeriod = 5 * time.Second
+
+// serializes assignment of reviewers between replicas
+const lockAssign = "assign"
+
+// isLeader reports whether this replica runs the job, it takes over the job when leader is gone
+func (a *App) isLeader(lock *db.Lock) bool {
+	ok, err := lock.Acquire()
+	if err != nil {
+		log.Println(ce.Wrap(err, "leader election"))
+		return false
+	}
+	return ok
+}
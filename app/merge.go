@@ -0,0 +1,47 @@
+package app
+
+import (
+	"fmt"
+	"log"
+
+	ce "tgj-bot/custom_errors"
+	gl "tgj-bot/external_service/gitlab"
+	"tgj-bot/models"
+	"tgj-bot/templates"
+)
+
+// MergeConfig sets what happens when all reviewers approved mr
+type MergeConfig struct {
+	// author is told that mr is ready to merge, in private chat if it is chosen in /settings
+	NotifyAuthor bool `json:"notify_author"`
+	// mr is set to be merged when pipeline succeeds, mrs with conflicts or failing pipeline are skipped
+	AutoMerge bool `json:"auto_merge"`
+}
+
+// readyToMerge tells author that mr is approved, it is set to auto merge by config.
+// gitlabMR is nil if it was not loaded, conflicts are unknown then
+func (a *App) readyToMerge(mr models.MR, gitlabMR *gl.GitlabMR) error {
+	data := readyToMergeData{URL: mr.URL, Title: mrTitle(mr)}
+	if gitlabMR != nil {
+		data.HasConflicts = gitlabMR.HasConflicts()
+		if a.Config.Merge.AutoMerge && !data.HasConflicts && !models.IsPipelineFailed(gitlabMR.PipelineStatus) {
+			if err := a.Gitlab.MergeWhenPipelineSucceeds(mr.GitlabID, gitlabMR.SHA); err != nil {
+				log.Println(ce.Wrap(err, fmt.Sprintf("auto merge mr_id=%d", mr.ID)))
+			} else {
+				data.IsAutoMerge = true
+			}
+		}
+	}
+
+	if !a.Config.Merge.NotifyAuthor || mr.AuthorID == nil || *mr.AuthorID == 0 {
+		return nil
+	}
+	author, err := a.DB.GetUserByID(*mr.AuthorID)
+	if err != nil {
+		return err
+	}
+	data.Mention = mention(author.UserBrief)
+
+	a.notifyUser(author, fmt.Sprintf("merge:%d:%s", mr.ID, mr.HeadSHA), templates.ReadyToMerge, data)
+	return nil
+}
@@ -0,0 +1,31 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"tgj-bot/templates"
+)
+
+func TestRenderReadyToMerge(t *testing.T) {
+	tmpl, err := templates.New(templates.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := App{Templates: tmpl}
+
+	testCases := []struct {
+		data readyToMergeData
+		text string
+	}{
+		{readyToMergeData{Mention: "@alice"}, "@alice your merge request is approved and ready to merge"},
+		{readyToMergeData{Mention: "@alice", IsAutoMerge: true}, "will be merged when pipeline succeeds"},
+		{readyToMergeData{Mention: "@alice", HasConflicts: true}, "it has conflicts with the target branch"},
+	}
+
+	for i, tc := range testCases {
+		if msg := a.text(templates.ReadyToMerge, tc.data); !strings.Contains(msg, tc.text) {
+			t.Fatalf("failed at index %d: %q", i, msg)
+		}
+	}
+}
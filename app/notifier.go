@@ -10,12 +10,7 @@ import (
 	ce "tgj-bot/custom_errors"
 	"tgj-bot/external_service/jira"
 	"tgj-bot/models"
-)
-
-const (
-	greeting         = "🚀 Daily notification 🌞"
-	cutoff           = "-----------------------"
-	moveTaskToQAText = "please move task to QA:"
+	"tgj-bot/templates"
 )
 
 var (
@@ -27,8 +22,12 @@ var (
 		jira.PriorityLowest:  "🔹",
 	}
 	readyToQAEmoji = "✈️"
-	pointEmoji     = []string{"🔹", "🔸"}
-	sadEmoji       = []string{"😀", "😁", "😂", "🤣", "😃", "😄", "😅", "😆", "😉", "😊", "😋", "😎", "☺", "️🙂", "🤗", "🤔", "😐",
+	// thread waits for reply of mr author
+	authorTurnEmoji = "❓"
+	// mr author replied to thread of reviewer
+	reviewerTurnEmoji = "↩️"
+	pointEmoji        = []string{"🔹", "🔸"}
+	sadEmoji          = []string{"😀", "😁", "😂", "🤣", "😃", "😄", "😅", "😆", "😉", "😊", "😋", "😎", "☺", "️🙂", "🤗", "🤔", "😐",
 		"😑", "😶", "🙄", "😏", "😣", "😥", "😮", "🤐", "😯", "😪", "😫", "😴", "😌", "😛", "😜", "😝", "🤤", "😒", "😓",
 		"😔", "😕", "🙃", "🤑", "😲", "☹", "️🙁", "😖", "😞", "😟", "😤", "😢", "😭", "😦", "😧", "😨", "😩"}
 	joyEmoji = []string{"😉", "😃", "😄", "😁", "😆", "😅", "😂", "🤣", "☺", "️😊", "😇", "🙂", "🙃", "😉", "😌", "😍", "😘",
@@ -45,10 +44,14 @@ func (a *App) notify() {
 		log.Println("Notifications does not allow in config")
 		return
 	}
+	lock := a.DB.NewLock(jobNotify)
 	go func() {
 		var curDay time.Weekday
 		for t := range time.Tick(time.Duration(a.Config.Timings.CheckNotifyPeriod)) {
-			lastSendNotify, err := a.loadLastSendNotify()
+			if !a.isLeader(lock) {
+				continue
+			}
+			lastSendNotify, prev, err := a.loadLastSendNotify()
 			if err != nil {
 				a.logError(err)
 				continue
@@ -63,12 +66,14 @@ func (a *App) notify() {
 			}
 
 			if t.Hour() >= a.Config.Notifier.TimeHour && t.Minute() >= a.Config.Notifier.TimeMinute {
-				if err := a.sendDailyNotification(); err != nil {
+				// day is claimed after reminders are enqueued, so they are sent again if enqueueing fails.
+				// Dedup key of the day keeps replica which lost leadership meanwhile from sending them twice
+				if err := a.sendDailyNotification("daily:" + t.Format("2006-01-02")); err != nil {
 					a.logError(err)
+					continue
 				}
-
 				value := models.LastSendNotifyOption{Stamp: time.Now().Unix()}
-				if err := a.DB.UpdateOptionByName(models.OptionLastSendNotify, value); err != nil {
+				if _, err := a.DB.CompareAndSwapOption(models.OptionLastSendNotify, prev, value); err != nil {
 					a.logError(err)
 				}
 			}
@@ -76,11 +81,13 @@ func (a *App) notify() {
 	}()
 }
 
-func (a *App) loadLastSendNotify() (value time.Time, err error) {
+// loadLastSendNotify returns time of the last daily notification and raw option to compare and swap it
+func (a *App) loadLastSendNotify() (value time.Time, raw string, err error) {
 	option, err := a.DB.LoadOptionByName(models.OptionLastSendNotify)
 	if err != nil {
 		return
 	}
+	raw = option.Item
 
 	var item models.LastSendNotifyOption
 	err = json.Unmarshal([]byte(option.Item), &item)
@@ -91,7 +98,8 @@ func (a *App) loadLastSendNotify() (value time.Time, err error) {
 	return
 }
 
-func (a *App) sendDailyNotification() error {
+// sendDailyNotification enqueues reminders and tries to deliver them at once, non-empty dedup key prevents sending them twice
+func (a *App) sendDailyNotification(dedupKey string) error {
 	us, err := a.DB.GetActiveUsers()
 	if err != nil {
 		log.Println(ce.Wrap(err, "notifier update reviews"))
@@ -99,40 +107,76 @@ func (a *App) sendDailyNotification() error {
 	}
 	log.Printf("Notifier active users %v", us)
 
+	now := time.Now()
 	messagesCount := 0
-	msg := greeting + "\n"
+	groupCount := 0
+	msg := a.text(templates.DailyGreeting, nil) + "\n"
 	for _, u := range us {
-		qaTaskStr, err := a.buildNotifierQATask(u.ID)
+		qaTasks, err := a.buildNotifierQATask(u.ID)
 		if err != nil {
 			log.Println(ce.Wrap(err, "notifier qa task"))
 			continue
 		}
 
-		mrStr, err := a.buildNotifierMRString(u.ID)
+		reviews, err := a.buildNotifierMRString(u.ID)
 		if err != nil {
 			log.Println(ce.Wrap(err, "notifier update reviews"))
 			continue
 		}
 
-		log.Printf("Notifier string for user %d: %s %s\n", u.ID, mrStr, qaTaskStr)
+		threads, err := a.buildNotifierThreads(u.UserBrief)
+		if err != nil {
+			log.Println(ce.Wrap(err, "notifier threads"))
+			continue
+		}
+
+		log.Printf("Notifier lines for user %d: %v %v %v\n", u.ID, reviews, qaTasks, threads)
 
-		if len(mrStr) > 0 || len(qaTaskStr) > 0 {
-			msg += fmt.Sprintf("%s\n@%s %s\n%s%s", cutoff, u.TelegramUsername, randSadEmoji(), qaTaskStr, mrStr)
-			messagesCount++
+		if len(reviews) == 0 && len(qaTasks) == 0 && len(threads) == 0 {
+			continue
+		}
+		messagesCount++
+
+		data := dailyData{
+			Mention: mention(u.UserBrief),
+			Emoji:   randSadEmoji(),
+			Tasks:   qaTasks,
+			Reviews: reviews,
+			Threads: threads,
+		}
+		group, dm, dmAt := a.userDelivery(u, now)
+		if dm {
+			userKey := ""
+			if dedupKey != "" {
+				userKey = fmt.Sprintf("%s:%d", dedupKey, u.ID)
+			}
+			if err := a.sendDirectMessage(u, userKey, templates.DailyDirect, data, dmAt); err != nil {
+				log.Println(ce.Wrap(err, "notifier direct message"))
+				group = true
+			}
+		}
+		if group {
+			msg += a.text(templates.DailyUser, data)
+			groupCount++
 		}
 	}
 	if messagesCount == 0 {
 		msg += "\n" + a.praise()
+	} else if groupCount == 0 {
+		// everyone got personal reminders
+		return nil
 	} else {
 		msg += "\n" + a.motivate()
 	}
-	a.Telegram.SendMessage(msg)
-
+	if _, err = a.enqueue(a.Config.Tg.ChatID, dedupKey, msg, nil, time.Time{}); err != nil {
+		return err
+	}
+	a.flush(a.Config.Tg.ChatID)
 	return nil
 }
 
-func (a *App) buildNotifierQATask(uID int) (str string, err error) {
-	mrs, err := a.DB.GetUserClosedMRs(uID, jira.StatusOnReview)
+func (a *App) buildNotifierQATask(uID int) (lines []mrLine, err error) {
+	mrs, err := a.DB.GetUserReviewedMRs(uID, jira.StatusOnReview)
 	if err != nil {
 		err = ce.WrapWithLog(err, "notifier build message")
 		return
@@ -140,13 +184,13 @@ func (a *App) buildNotifierQATask(uID int) (str string, err error) {
 	log.Printf("User %d mrs:%d\n", uID, len(mrs))
 
 	for _, mr := range mrs {
-		str += fmt.Sprintf("%s %s\n", readyToQAEmoji, mr.URL)
+		lines = append(lines, mrLine{Emoji: readyToQAEmoji, URL: mr.URL, Title: mrTitle(mr)})
 	}
 
 	return
 }
 
-func (a *App) buildNotifierMRString(uID int) (s string, err error) {
+func (a *App) buildNotifierMRString(uID int) (lines []mrLine, err error) {
 	rs, err := a.DB.GetOpenedReviewsByUserID(uID)
 	if err != nil {
 		err = ce.WrapWithLog(err, "notifier build message")
@@ -159,10 +203,45 @@ func (a *App) buildNotifierMRString(uID int) (s string, err error) {
 			mr, err := a.DB.GetMrByID(r.MrID)
 			if err != nil {
 				err = ce.WrapWithLog(err, "notifier build message")
-				return s, err
+				return lines, err
+			}
+			if a.isReminderPaused(mr.PipelineStatus) {
+				continue
 			}
-			s += fmt.Sprintf("%s %s\n", getPriorityEmoji(mr.JiraPriority), mr.URL)
+			emoji := getPriorityEmoji(mr.JiraPriority)
+			if models.IsPipelineFailed(mr.PipelineStatus) {
+				emoji = pipelineFailedEmoji
+			}
+			lines = append(lines, mrLine{Emoji: emoji, URL: mr.URL, Title: mrTitle(mr)})
+		}
+	}
+	return
+}
+
+// buildNotifierThreads returns links to threads waiting for reply of the user, as mr author or as reviewer
+func (a *App) buildNotifierThreads(u models.UserBrief) (lines []mrLine, err error) {
+	if u.GitlabID == 0 {
+		return
+	}
+	ts, err := a.DB.GetAwaitingThreads(u.GitlabID)
+	if err != nil {
+		return
+	}
+
+	mrs := make(map[int]models.MR)
+	for _, t := range ts {
+		mr, ok := mrs[t.MrID]
+		if !ok {
+			if mr, err = a.DB.GetMrByID(t.MrID); err != nil {
+				return
+			}
+			mrs[t.MrID] = mr
+		}
+		emoji := authorTurnEmoji
+		if t.OpenedBy == u.GitlabID {
+			emoji = reviewerTurnEmoji
 		}
+		lines = append(lines, mrLine{Emoji: emoji, URL: fmt.Sprintf("%s#note_%d", mr.URL, t.NoteID), Title: mrTitle(mr)})
 	}
 	return
 }
@@ -173,8 +252,7 @@ func (a *App) notifyReviewTask(mr models.MR) error {
 		return err
 	}
 
-	msg := fmt.Sprintf("%s @%s, %s %s", readyToQAEmoji, user.TelegramUsername, moveTaskToQAText, mr.URL)
-	a.Telegram.SendMessage(msg)
+	a.sendMessageOnce(fmt.Sprintf("qa:%d", mr.ID), a.text(templates.MoveTaskToQA, userMrData{Mention: mention(user.UserBrief), URL: mr.URL, Title: mrTitle(mr)}))
 
 	return nil
 }
@@ -194,10 +272,10 @@ func getPriorityEmoji(priority int) string {
 	return ""
 }
 
-func (a App) praise() string {
-	return a.Config.Notifier.Praise[rand.Intn(len(a.Config.Notifier.Praise))] + " " + randJoyEmoji()
+func (a *App) praise() string {
+	return a.randText(templates.Praise) + " " + randJoyEmoji()
 }
 
-func (a App) motivate() string {
-	return a.Config.Notifier.Motivate[rand.Intn(len(a.Config.Notifier.Motivate))] + " " + randJoyEmoji()
+func (a *App) motivate() string {
+	return a.randText(templates.Motivate) + " " + randJoyEmoji()
 }
@@ -0,0 +1,257 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	ce "tgj-bot/custom_errors"
+	db "tgj-bot/external_service/database"
+	tg "tgj-bot/external_service/telegram"
+	"tgj-bot/models"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+const (
+	outboxBatchSize   = 50
+	outboxMaxAttempts = 10
+	// claimed message is hidden from other senders while it is being delivered
+	outboxLease      = 30 * time.Second
+	outboxMinBackoff = 5 * time.Second
+	outboxMaxBackoff = time.Hour
+)
+
+func (a *App) deliverOutbox() {
+	lock := a.DB.NewLock(jobOutbox)
+	go func() {
+		for t := range time.Tick(a.Config.Timings.deliverOutboxPeriod()) {
+			if !a.isLeader(lock) {
+				continue
+			}
+			if err := a.deliverDueMessages(t); err != nil {
+				log.Println(ce.Wrap(err, "deliver outbox"))
+			}
+		}
+	}()
+}
+
+func (a *App) deliverDueMessages(now time.Time) error {
+	_, err := a.deliverClaimed(0, now)
+	return err
+}
+
+// deliverClaimed sends due messages of the chat, zero chat id means all chats.
+// Messages of the chat are delivered in order, so the rest waits for failed one.
+// Returns the first delivery error
+func (a *App) deliverClaimed(chatID int64, now time.Time) (deliveryErr error, err error) {
+	ms, err := a.DB.ClaimDueMessages(now.Unix(), now.Add(outboxLease).Unix(), chatID, outboxBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	failed := make(map[int64]bool)
+	for _, m := range ms {
+		if failed[m.ChatID] {
+			continue
+		}
+		if err := a.deliver(m, now); err != nil {
+			failed[m.ChatID] = true
+			if deliveryErr == nil {
+				deliveryErr = err
+			}
+		}
+	}
+	return deliveryErr, nil
+}
+
+// deliver sends outbox message and records the result of attempt
+func (a *App) deliver(m models.OutboxMessage, now time.Time) error {
+	messageID, err := a.sendOrEdit(m)
+	if err == nil {
+		if err := a.DB.MarkMessageSent(m.ID, messageID, now.Unix()); err != nil {
+			log.Println(ce.Wrap(err, "deliver"))
+		}
+		return nil
+	}
+
+	if tg.IsForbidden(err) || tg.IsBadRequest(err) || m.Attempts+1 >= outboxMaxAttempts {
+		if err := a.DB.MarkMessageFailed(m.ID, err.Error()); err != nil {
+			log.Println(ce.Wrap(err, "deliver"))
+		}
+		return err
+	}
+	next := now.Add(retryDelay(m.Attempts, err))
+	if err := a.DB.MarkMessageRetry(m.ID, next.Unix(), err.Error()); err != nil {
+		log.Println(ce.Wrap(err, "deliver"))
+	}
+	return err
+}
+
+// sendOrEdit sends new message or replaces text of the message being edited, returns id of the chat message
+func (a *App) sendOrEdit(m models.OutboxMessage) (int, error) {
+	if m.EditMessageID == 0 {
+		var replyMarkup interface{}
+		if m.ReplyMarkup != "" {
+			replyMarkup = json.RawMessage(m.ReplyMarkup)
+		}
+		return a.Telegram.Send(m.ChatID, m.Text, replyMarkup)
+	}
+
+	var keyboard *tgbotapi.InlineKeyboardMarkup
+	if m.ReplyMarkup != "" {
+		keyboard = new(tgbotapi.InlineKeyboardMarkup)
+		if err := json.Unmarshal([]byte(m.ReplyMarkup), keyboard); err != nil {
+			return 0, ce.Wrap(err, "edit reply markup")
+		}
+	}
+	return m.EditMessageID, a.Telegram.EditMessage(m.ChatID, m.EditMessageID, m.Text, keyboard)
+}
+
+// retryDelay returns delay requested by telegram or exponential backoff by number of failed attempts
+func retryDelay(attempts int, err error) time.Duration {
+	if d := tg.RetryAfter(err); d > 0 {
+		return d
+	}
+	d := outboxMinBackoff
+	for i := 0; i < attempts && d < outboxMaxBackoff; i++ {
+		d *= 2
+	}
+	if d > outboxMaxBackoff {
+		d = outboxMaxBackoff
+	}
+	return d
+}
+
+// enqueue puts message split by telegram length limit to the outbox, it is delivered by outbox worker
+// not earlier than at, zero time means the message is due at once.
+// Returns only new messages, parts already enqueued with the same dedup key are skipped
+func (a *App) enqueue(chatID int64, dedupKey, msg string, replyMarkup interface{}, at time.Time) ([]models.OutboxMessage, error) {
+	return a.enqueueTo(&a.DB, chatID, dedupKey, msg, replyMarkup, at)
+}
+
+// enqueueTo puts message to the outbox by database client, message enqueued by transaction client
+// is delivered only when transaction is committed
+func (a *App) enqueueTo(q *db.Client, chatID int64, dedupKey, msg string, replyMarkup interface{}, at time.Time) (ms []models.OutboxMessage, err error) {
+	// telegram rejects empty messages, e.g. template failed to render
+	if msg == "" {
+		return nil, errors.New("empty message is not enqueued")
+	}
+	markup, err := marshalMarkup(replyMarkup)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if at.IsZero() {
+		at = now
+	}
+	parts := tg.SplitMessage(msg, tg.MaxMessageLength)
+	for i, part := range parts {
+		m := models.OutboxMessage{
+			ChatID:        chatID,
+			Text:          part,
+			DedupKey:      dedupKey,
+			NextAttemptAt: at.Unix(),
+			CreatedAt:     now.Unix(),
+		}
+		if i == len(parts)-1 {
+			m.ReplyMarkup = markup
+		}
+		if dedupKey != "" && len(parts) > 1 {
+			m.DedupKey = fmt.Sprintf("%s:%d", dedupKey, i)
+		}
+		if m.ID, err = q.EnqueueMessage(m); err != nil {
+			return
+		}
+		if m.ID != 0 {
+			ms = append(ms, m)
+		}
+	}
+	return
+}
+
+func marshalMarkup(replyMarkup interface{}) (string, error) {
+	if replyMarkup == nil {
+		return "", nil
+	}
+	markup, err := json.Marshal(replyMarkup)
+	if err != nil {
+		return "", ce.Wrap(err, "enqueue reply markup")
+	}
+	return string(markup), nil
+}
+
+// edit puts new text of team chat message to the outbox and tries to deliver it at once,
+// nil keyboard removes buttons. Nothing is enqueued if the latest version of message is the same
+func (a *App) edit(messageID int, msg string, keyboard *tgbotapi.InlineKeyboardMarkup) error {
+	if msg == "" {
+		return errors.New("empty message is not enqueued")
+	}
+	var replyMarkup interface{}
+	if keyboard != nil {
+		replyMarkup = keyboard
+	}
+	markup, err := marshalMarkup(replyMarkup)
+	if err != nil {
+		return err
+	}
+	chatID := a.Config.Tg.ChatID
+	last, err := a.DB.GetLastMessageVersion(chatID, messageID)
+	if err != nil {
+		return err
+	}
+	if last.ID != 0 && last.Text == msg && last.ReplyMarkup == markup {
+		return nil
+	}
+
+	now := time.Now()
+	m := models.OutboxMessage{
+		ChatID:        chatID,
+		Text:          msg,
+		ReplyMarkup:   markup,
+		EditMessageID: messageID,
+		NextAttemptAt: now.Unix(),
+		CreatedAt:     now.Unix(),
+	}
+	if _, err = a.DB.EnqueueMessage(m); err != nil {
+		return err
+	}
+	a.flush(chatID)
+	return nil
+}
+
+// send puts message to the outbox and tries to deliver it at once, so message is retried
+// by outbox worker when telegram is not available. Messages with the same non-empty
+// dedup key are sent only once. Error is returned if bot is not allowed to write to the chat
+func (a *App) send(chatID int64, dedupKey, msg string, replyMarkup interface{}) error {
+	if _, err := a.enqueue(chatID, dedupKey, msg, replyMarkup, time.Time{}); err != nil {
+		return err
+	}
+	if err := a.flush(chatID); tg.IsForbidden(err) {
+		return err
+	}
+	return nil
+}
+
+// flush delivers due messages of the chat in order of enqueueing, messages
+// postponed after failed one are delivered by outbox worker. Returns delivery error
+func (a *App) flush(chatID int64) error {
+	deliveryErr, err := a.deliverClaimed(chatID, time.Now())
+	if err != nil {
+		log.Println(ce.Wrap(err, "flush outbox"))
+	}
+	return deliveryErr
+}
+
+// sendMessage sends message to the team chat
+func (a *App) sendMessage(msg string) {
+	a.sendMessageOnce("", msg)
+}
+
+func (a *App) sendMessageOnce(dedupKey, msg string) {
+	if err := a.send(a.Config.Tg.ChatID, dedupKey, msg, nil); err != nil {
+		log.Println(ce.Wrap(err, "send message"))
+	}
+}
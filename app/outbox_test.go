@@ -0,0 +1,41 @@
+package app
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+func TestRetryDelay(t *testing.T) {
+	rateLimit := tgbotapi.Error{Message: "Too Many Requests", ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 42}}
+	testCases := []struct {
+		attempts int
+		err      error
+		delay    time.Duration
+	}{
+		{0, errors.New("timeout"), 5 * time.Second},
+		{1, errors.New("timeout"), 10 * time.Second},
+		{3, errors.New("timeout"), 40 * time.Second},
+		{20, errors.New("timeout"), time.Hour},
+		{5, rateLimit, 42 * time.Second},
+	}
+
+	for i, tc := range testCases {
+		if delay := retryDelay(tc.attempts, tc.err); delay != tc.delay {
+			t.Fatalf("failed at index %d: got %v", i, delay)
+		}
+	}
+}
+
+func TestTimingsConf_Defaults(t *testing.T) {
+	var cfg TimingsConf
+	if cfg.deliverOutboxPeriod() != defaultDeliverOutboxPeriod || cfg.checkEscalationPeriod() != defaultCheckEscalationPeriod {
+		t.Fatalf("defaults are not applied")
+	}
+	cfg = TimingsConf{DeliverOutboxPeriod: JSONDuration(time.Second), CheckEscalationPeriod: JSONDuration(time.Minute)}
+	if cfg.deliverOutboxPeriod() != time.Second || cfg.checkEscalationPeriod() != time.Minute {
+		t.Fatalf("configured periods are not used")
+	}
+}
@@ -0,0 +1,103 @@
+package app
+
+import (
+	"strings"
+
+	"tgj-bot/models"
+	"tgj-bot/templates"
+)
+
+var errOverrideUsage = newUserError(templates.ErrOverrideUsage, "")
+
+// reviewOverrides are reviewers chosen by the author of /mr, the rest seats are picked by payload
+type reviewOverrides struct {
+	// lowercase telegram usernames without @
+	forced   []string
+	excluded []string
+	// seats in addition to the review party from config
+	extra ReviewParty
+}
+
+// parseReviewOverrides parses /mr arguments after url: @alice -@carol +lead
+func parseReviewOverrides(args []string) (o reviewOverrides, err error) {
+	for _, arg := range args {
+		switch {
+		case arg == "+"+string(models.Developer):
+			o.extra.DevNum++
+		case arg == "+"+string(models.Lead):
+			o.extra.LeadNum++
+		case strings.HasPrefix(arg, "-@") && len(arg) > 2:
+			o.excluded = append(o.excluded, arg[2:])
+		case strings.HasPrefix(arg, "@") && len(arg) > 1:
+			o.forced = append(o.forced, arg[1:])
+		default:
+			return o, errOverrideUsage
+		}
+	}
+	return
+}
+
+// checkForcedReviewers explains why forced reviewer is not among users available for review
+func (a *App) checkForcedReviewers(users models.UsersPayload, forced []string, author models.User) error {
+	available := make([]string, 0, len(users))
+	for _, u := range users {
+		available = append(available, u.TelegramUsername)
+	}
+	for _, username := range forced {
+		if hasUsername(available, username) {
+			continue
+		}
+		u, err := a.userByUsername(username)
+		if err != nil {
+			return err
+		}
+		if author.ID != 0 && u.ID == author.ID {
+			return newUserError(templates.ErrUserIsAuthor, username)
+		}
+		if !u.IsActive {
+			return newUserError(templates.ErrUserInactive, username)
+		}
+		return newUserError(templates.ErrNoReviewers, username)
+	}
+	return nil
+}
+
+// getParticipants picks review party: forced reviewers take seats of their role, the rest seats
+// are filled by users with the least payload except excluded ones
+func getParticipants(users models.UsersPayload, cfg ReviewParty, o reviewOverrides) (rp models.UsersPayload, err error) {
+	seats := map[models.Role]int{
+		models.Developer: cfg.DevNum + o.extra.DevNum,
+		models.Lead:      cfg.LeadNum + o.extra.LeadNum,
+	}
+	candidates := make(models.UsersPayload, 0, len(users))
+	for _, u := range users {
+		switch {
+		case hasUsername(o.forced, u.TelegramUsername):
+			rp = append(rp, u)
+			seats[u.Role]--
+		case !hasUsername(o.excluded, u.TelegramUsername):
+			candidates = append(candidates, u)
+		}
+	}
+
+	for _, role := range []models.Role{models.Developer, models.Lead} {
+		if seats[role] <= 0 {
+			continue
+		}
+		picked, err := candidates.GetN(seats[role], role)
+		if err != nil {
+			return nil, err
+		}
+		rp = append(rp, picked...)
+	}
+	return rp, nil
+}
+
+func hasUsername(usernames []string, username string) bool {
+	for _, u := range usernames {
+		if u == username && u != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package app
+
+import (
+	"testing"
+
+	"tgj-bot/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReviewOverrides(t *testing.T) {
+	tests := []struct {
+		args   []string
+		exp    reviewOverrides
+		expErr error
+	}{
+		{nil, reviewOverrides{}, nil},
+		{[]string{"@alice", "@bob"}, reviewOverrides{forced: []string{"alice", "bob"}}, nil},
+		{[]string{"-@carol", "+lead", "+lead", "+dev"}, reviewOverrides{excluded: []string{"carol"}, extra: ReviewParty{LeadNum: 2, DevNum: 1}}, nil},
+		{[]string{"alice"}, reviewOverrides{}, errOverrideUsage},
+		{[]string{"@"}, reviewOverrides{}, errOverrideUsage},
+		{[]string{"+admin"}, reviewOverrides{}, errOverrideUsage},
+	}
+
+	for index, item := range tests {
+		o, err := parseReviewOverrides(item.args)
+		if err != item.expErr || (err == nil && !assert.Equal(t, item.exp, o)) {
+			t.Fatalf("failed at index %d", index)
+		}
+	}
+}
+
+func TestGetParticipants(t *testing.T) {
+	user := func(username string, role models.Role) models.UserPayload {
+		return models.UserPayload{UserBrief: models.UserBrief{TelegramUsername: username, Role: role}}
+	}
+	// sorted by payload
+	users := models.UsersPayload{
+		user("alice", models.Developer),
+		user("bob", models.Developer),
+		user("carol", models.Developer),
+		user("lead1", models.Lead),
+		user("lead2", models.Lead),
+	}
+	cfg := ReviewParty{DevNum: 2, LeadNum: 1}
+
+	tests := []struct {
+		o   reviewOverrides
+		exp []string
+	}{
+		{reviewOverrides{}, []string{"alice", "bob", "lead1"}},
+		{reviewOverrides{forced: []string{"carol"}}, []string{"carol", "alice", "lead1"}},
+		{reviewOverrides{forced: []string{"carol", "bob", "alice"}}, []string{"alice", "bob", "carol", "lead1"}},
+		{reviewOverrides{excluded: []string{"alice", "lead1"}}, []string{"bob", "carol", "lead2"}},
+		{reviewOverrides{forced: []string{"lead2"}, extra: ReviewParty{LeadNum: 1}}, []string{"lead2", "alice", "bob", "lead1"}},
+	}
+
+	for index, item := range tests {
+		rp, err := getParticipants(users, cfg, item.o)
+		if err != nil {
+			t.Fatalf("failed at index %d: %v", index, err)
+		}
+		var usernames []string
+		for _, u := range rp {
+			usernames = append(usernames, u.TelegramUsername)
+		}
+		if !assert.Equal(t, item.exp, usernames) {
+			t.Fatalf("failed at index %d", index)
+		}
+	}
+}
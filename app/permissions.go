@@ -0,0 +1,112 @@
+package app
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	ce "tgj-bot/custom_errors"
+	"tgj-bot/models"
+	"tgj-bot/templates"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+var (
+	errAdminOnly        = newUserError(templates.ErrAdminOnly, "")
+	errChangeOtherUser  = newUserError(templates.ErrChangeOtherUser, "")
+	errRegisterPrivRole = newUserError(templates.ErrRegisterPrivRole, "")
+)
+
+// permission checks whether the caller may run the command with lowercase args
+type permission func(caller models.User, args []string) error
+
+func adminOnly(caller models.User, _ []string) error {
+	if !caller.IsAdmin {
+		return errAdminOnly
+	}
+	return nil
+}
+
+// selfOrAdmin allows to change other users to admins only, target user is the first argument
+func selfOrAdmin(caller models.User, args []string) error {
+	if len(args) == 0 || caller.IsAdmin || strings.TrimPrefix(args[0], "@") == caller.TelegramUsername {
+		return nil
+	}
+	return errChangeOtherUser
+}
+
+// ownReviewOrAdmin allows to move reviews of other users to admins only, reviewer is the second argument
+func ownReviewOrAdmin(caller models.User, args []string) error {
+	if len(args) < 2 || !strings.HasPrefix(args[1], "@") {
+		return nil
+	}
+	return selfOrAdmin(caller, args[1:])
+}
+
+// canRegister allows to claim lead role to admins only, role is the second argument
+func canRegister(caller models.User, args []string) error {
+	if len(args) < 2 || models.Role(args[1]) != models.Lead || caller.IsAdmin {
+		return nil
+	}
+	return errRegisterPrivRole
+}
+
+// allow checks access and permission of the command for the caller
+func (spec commandSpec) allow(caller models.User, args string) error {
+	if spec.access == accessAdmin {
+		if err := adminOnly(caller, nil); err != nil {
+			return err
+		}
+	}
+	if spec.permission != nil {
+		return spec.permission(caller, strings.Fields(strings.ToLower(args)))
+	}
+	return nil
+}
+
+// checkPermission enforces command access and permission, denials are recorded to the audit log
+func (a *App) checkPermission(ctx *commandContext) error {
+	args := ctx.update.Message.CommandArguments()
+	err := ctx.spec.allow(ctx.caller, args)
+	if err == nil {
+		return nil
+	}
+
+	r := models.AuditRecord{
+		TelegramID:       ctx.caller.TelegramID,
+		TelegramUsername: ctx.caller.TelegramUsername,
+		Command:          string(ctx.spec.name),
+		Args:             args,
+		Reason:           err.Error(),
+		CreatedAt:        time.Now().Unix(),
+	}
+	if auditErr := a.DB.SaveAuditRecord(r); auditErr != nil {
+		log.Println(ce.Wrap(auditErr, "audit"))
+	}
+	return err
+}
+
+// caller returns registered user who sent the update, unregistered user has no rights
+func (a *App) caller(from *tgbotapi.User) models.User {
+	tgID := strconv.Itoa(from.ID)
+	u, err := a.DB.GetUserByTgID(tgID)
+	if err != nil {
+		u = models.User{UserBrief: models.UserBrief{TelegramID: tgID, TelegramUsername: strings.ToLower(from.UserName)}}
+	}
+	return u
+}
+
+// bootstrapAdmins grants admin on start to registered users whose telegram ids are listed in config.
+// Usernames are not trusted, they can be changed and taken by another account
+func (a *App) bootstrapAdmins() error {
+	if len(a.Config.Admins) == 0 {
+		return nil
+	}
+	admins := make([]string, 0, len(a.Config.Admins))
+	for _, admin := range a.Config.Admins {
+		admins = append(admins, strconv.FormatInt(admin, 10))
+	}
+	return a.DB.GrantAdmins(admins)
+}
@@ -0,0 +1,58 @@
+package app
+
+import (
+	"testing"
+
+	"tgj-bot/models"
+)
+
+func TestCommandPermissions(t *testing.T) {
+	user := models.User{UserBrief: models.UserBrief{TelegramUsername: "alice"}}
+	admin := models.User{UserBrief: models.UserBrief{TelegramUsername: "bob"}, IsAdmin: true}
+	noUsername := models.User{UserBrief: models.UserBrief{TelegramID: "42"}}
+
+	tests := []struct {
+		cmd    command
+		caller models.User
+		args   string
+		expErr error
+	}{
+		{inactiveCmd, user, "", nil},
+		{inactiveCmd, user, "alice", nil},
+		{inactiveCmd, user, "@Alice", nil},
+		{inactiveCmd, user, "carol", errChangeOtherUser},
+		{activeCmd, user, "carol", errChangeOtherUser},
+		{activeCmd, admin, "carol", nil},
+		{registerCmd, user, "42", nil},
+		{registerCmd, user, "42 dev", nil},
+		{registerCmd, user, "42 lead", errRegisterPrivRole},
+		{registerCmd, admin, "42 lead", nil},
+		{dailyCmd, user, "", errAdminOnly},
+		{dailyCmd, admin, "", nil},
+		{mrCmd, user, "url", nil},
+		{roleCmd, user, "carol lead", errAdminOnly},
+		{roleCmd, admin, "carol lead", nil},
+		{unregisterCmd, user, "", nil},
+		{unregisterCmd, user, "carol", errChangeOtherUser},
+		{unregisterCmd, admin, "carol", nil},
+		{unregisterCmd, noUsername, "", nil},
+		{reassignCmd, user, "url", nil},
+		{reassignCmd, user, "url @alice @carol", nil},
+		{reassignCmd, user, "url @carol", errChangeOtherUser},
+		{reassignCmd, user, "url on vacation", nil},
+		{reassignCmd, admin, "url @carol @alice", nil},
+		{swapCmd, user, "url @carol", nil},
+		{unregisterCmd, noUsername, "carol", errChangeOtherUser},
+	}
+
+	r := (&App{}).commands()
+	for index, item := range tests {
+		spec, ok := r.lookup(item.cmd, scopeGroup)
+		if !ok {
+			t.Fatalf("failed at index %d: command not found", index)
+		}
+		if err := spec.allow(item.caller, item.args); err != item.expErr {
+			t.Fatalf("failed at index %d: unexpected err %v", index, err)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package app
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	gl "tgj-bot/external_service/gitlab"
+	"tgj-bot/models"
+	"tgj-bot/templates"
+)
+
+const pipelineFailedEmoji = "🔴"
+
+// PipelineConfig makes review depend on ci pipeline of mr head, failing mrs are marked in /queue and reminders anyway
+type PipelineConfig struct {
+	// reviewers are assigned after pipeline passes, until then mr is pending
+	WaitForSuccess bool `json:"wait_for_success"`
+	// reviewers are not reminded while pipeline is failing, waiting time starts over when it is fixed
+	PauseReminders bool `json:"pause_reminders"`
+	// author is notified when pipeline of mr under review fails
+	NotifyAuthor bool `json:"notify_author"`
+}
+
+// isReadyForReview reports whether reviewers may be assigned to mr
+func (a *App) isReadyForReview(gitlabMR *gl.GitlabMR) bool {
+	if gitlabMR.IsDraft {
+		return false
+	}
+	return !a.Config.Pipeline.WaitForSuccess || models.IsPipelinePassed(gitlabMR.PipelineStatus)
+}
+
+// isReminderPaused reports whether reviewers of mr are not reminded because of failing pipeline
+func (a *App) isReminderPaused(pipelineStatus string) bool {
+	return a.Config.Pipeline.PauseReminders && models.IsPipelineFailed(pipelineStatus)
+}
+
+// checkPipeline saves pipeline status of mr, author is notified when it breaks
+func (a *App) checkPipeline(mr models.MR, gitlabMR *gl.GitlabMR) error {
+	status := gitlabMR.PipelineStatus
+	if status == mr.PipelineStatus {
+		return nil
+	}
+	if err := a.DB.UpdateMrPipeline(mr.ID, status); err != nil {
+		return err
+	}
+	wasFailed, isFailed := models.IsPipelineFailed(mr.PipelineStatus), models.IsPipelineFailed(status)
+	// reviews of pending mr are not assigned yet
+	if mr.IsPending || wasFailed == isFailed {
+		return nil
+	}
+
+	if !isFailed {
+		if a.Config.Pipeline.PauseReminders {
+			return a.DB.ResetReviewsTime(mr.ID, time.Now().Unix())
+		}
+		return nil
+	}
+	if !a.Config.Pipeline.NotifyAuthor || mr.AuthorID == nil || *mr.AuthorID == 0 {
+		return nil
+	}
+	author, err := a.DB.GetUserByID(*mr.AuthorID)
+	if err != nil {
+		return err
+	}
+	log.Printf("pipeline of mr_id=%d is %s", mr.ID, status)
+	a.sendMessageOnce(fmt.Sprintf("pipeline:%d:%s", mr.ID, gitlabMR.SHA),
+		a.text(templates.PipelineFailed, userMrData{Mention: mention(author.UserBrief), URL: mr.URL, Title: mrTitle(mr)}))
+	return nil
+}
@@ -0,0 +1,39 @@
+package app
+
+import "sync"
+
+const defaultRefreshWorkers = 4
+
+// refreshWorkers returns number of mrs refreshed from gitlab at once
+func (a *App) refreshWorkers() int {
+	if a.Config.Gl.RefreshWorkers <= 0 {
+		return defaultRefreshWorkers
+	}
+	return a.Config.Gl.RefreshWorkers
+}
+
+// forEach calls fn for every index below n from bounded number of goroutines and waits for all of them
+func forEach(n, workers int, fn func(i int)) {
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > n {
+		workers = n
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
@@ -0,0 +1,52 @@
+package app
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestForEach(t *testing.T) {
+	testCases := []struct {
+		n, workers int
+	}{
+		{0, 4},
+		{1, 4},
+		{10, 3},
+		{5, 0},
+	}
+
+	for i, tc := range testCases {
+		var mu sync.Mutex
+		var running, maxRunning int
+		visited := make([]int, tc.n)
+		forEach(tc.n, tc.workers, func(j int) {
+			mu.Lock()
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			mu.Unlock()
+
+			time.Sleep(time.Millisecond)
+			visited[j]++
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+		})
+
+		for j, v := range visited {
+			if v != 1 {
+				t.Fatalf("failed at index %d: item %d visited %d times", i, j, v)
+			}
+		}
+		limit := tc.workers
+		if limit < 1 {
+			limit = 1
+		}
+		if maxRunning > limit {
+			t.Fatalf("failed at index %d: %d workers run at once", i, maxRunning)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package app
+
+import (
+	"sort"
+
+	"tgj-bot/models"
+	"tgj-bot/templates"
+)
+
+const (
+	pendingEmoji  = "📝"
+	reviewedEmoji = "👍"
+)
+
+// queueHandler lists open mrs with reviewers they are waiting for, mrs with failing pipeline are marked
+func (a *App) queueHandler() error {
+	mrs, err := a.DB.GetOpenedMRs()
+	if err != nil {
+		return err
+	}
+	sortQueue(mrs)
+
+	lines := make([]queueLine, 0, len(mrs))
+	for _, mr := range mrs {
+		line := queueLine{Emoji: queueEmoji(mr), URL: mr.URL, Title: mrTitle(mr)}
+		if !mr.IsPending {
+			reviewers, err := a.DB.GetReviewersByMrID(mr.ID)
+			if err != nil {
+				return err
+			}
+			for _, r := range reviewers {
+				if !r.IsApproved {
+					line.Waiting = append(line.Waiting, displayName(r.UserBrief))
+				}
+			}
+		}
+		lines = append(lines, line)
+	}
+	a.sendMessage(a.text(templates.Queue, lines))
+	return nil
+}
+
+// sortQueue puts mrs by jira priority, older first
+func sortQueue(mrs []models.MR) {
+	sort.SliceStable(mrs, func(i, j int) bool {
+		if mrs[i].JiraPriority != mrs[j].JiraPriority {
+			return mrs[i].JiraPriority > mrs[j].JiraPriority
+		}
+		return mrs[i].ID < mrs[j].ID
+	})
+}
+
+func queueEmoji(mr models.MR) string {
+	switch {
+	case mr.IsPending:
+		return pendingEmoji
+	case mr.IsStale():
+		return staleEmoji
+	case models.IsPipelineFailed(mr.PipelineStatus):
+		return pipelineFailedEmoji
+	case mr.IsReviewed:
+		return reviewedEmoji
+	default:
+		return getPriorityEmoji(mr.JiraPriority)
+	}
+}
@@ -0,0 +1,78 @@
+package app
+
+import (
+	"testing"
+
+	gl "tgj-bot/external_service/gitlab"
+	"tgj-bot/external_service/jira"
+	"tgj-bot/models"
+	"tgj-bot/templates"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueueEmoji(t *testing.T) {
+	testCases := []struct {
+		mr    models.MR
+		emoji string
+	}{
+		{models.MR{JiraPriority: jira.PriorityHighest}, priorityEmoji[jira.PriorityHighest]},
+		{models.MR{JiraPriority: jira.PriorityHighest, PipelineStatus: models.PipelineFailed}, pipelineFailedEmoji},
+		{models.MR{PipelineStatus: models.PipelineRunning, IsReviewed: true}, reviewedEmoji},
+		{models.MR{IsPending: true, PipelineStatus: models.PipelineFailed}, pendingEmoji},
+	}
+
+	for i, tc := range testCases {
+		if !assert.Equal(t, tc.emoji, queueEmoji(tc.mr)) {
+			t.Fatalf("failed at index %d", i)
+		}
+	}
+}
+
+func TestSortQueue(t *testing.T) {
+	mrs := []models.MR{
+		{ID: 3, JiraPriority: jira.PriorityLow},
+		{ID: 2, JiraPriority: jira.PriorityHighest},
+		{ID: 1, JiraPriority: jira.PriorityLow},
+	}
+	sortQueue(mrs)
+	assert.Equal(t, []int{2, 1, 3}, []int{mrs[0].ID, mrs[1].ID, mrs[2].ID})
+}
+
+func TestRenderQueue(t *testing.T) {
+	tmpl, err := templates.New(templates.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := App{Templates: tmpl}
+
+	lines := []queueLine{
+		{Emoji: pipelineFailedEmoji, URL: "url1", Title: "!1 Fix", Waiting: []string{"alice", "bob"}},
+		{Emoji: reviewedEmoji, URL: "url2", Title: "!2 Add"},
+	}
+	assert.Equal(t, "Review queue:\n🔴 <a href=\"url1\">!1 Fix</a> — alice, bob\n👍 <a href=\"url2\">!2 Add</a>\n",
+		a.text(templates.Queue, lines))
+	assert.Equal(t, "Review queue:\nempty 🎉\n", a.text(templates.Queue, []queueLine{}))
+}
+
+func TestIsReadyForReview(t *testing.T) {
+	testCases := []struct {
+		waitForSuccess bool
+		mr             gl.GitlabMR
+		isReady        bool
+	}{
+		{false, gl.GitlabMR{PipelineStatus: models.PipelineFailed}, true},
+		{false, gl.GitlabMR{IsDraft: true}, false},
+		{true, gl.GitlabMR{PipelineStatus: models.PipelineRunning}, false},
+		{true, gl.GitlabMR{PipelineStatus: models.PipelineFailed}, false},
+		{true, gl.GitlabMR{PipelineStatus: models.PipelineSuccess}, true},
+		{true, gl.GitlabMR{}, true},
+	}
+
+	for i, tc := range testCases {
+		a := App{Config: Config{Pipeline: PipelineConfig{WaitForSuccess: tc.waitForSuccess}}}
+		if a.isReadyForReview(&tc.mr) != tc.isReady {
+			t.Fatalf("failed at index %d", i)
+		}
+	}
+}
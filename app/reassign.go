@@ -0,0 +1,150 @@
+package app
+
+import (
+	"strconv"
+	"strings"
+
+	"tgj-bot/models"
+	"tgj-bot/templates"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// reasons of moving reviews made by the bot
+const (
+	reasonInactive   = "inactive"
+	reasonUnregister = "unregister"
+	reasonButton     = "reassign button"
+	reasonEscalation = "escalation"
+)
+
+var (
+	errReassignUsage = newUserError(templates.ErrReassignUsage, "")
+	errMrClosed      = newUserError(templates.ErrMrClosed, "")
+)
+
+// reassignArgs are arguments of /reassign and /swap: mr [@user...] [reason]
+type reassignArgs struct {
+	mr string
+	// lowercase telegram usernames without @
+	usernames []string
+	reason    string
+}
+
+// parseReassignArgs takes at most maxUsers usernames after mr, the rest is a reason kept as is
+func parseReassignArgs(argsStr string, maxUsers int) (ra reassignArgs, err error) {
+	args := strings.Fields(argsStr)
+	if len(args) == 0 {
+		return ra, errReassignUsage
+	}
+	ra.mr = args[0]
+	rest := args[1:]
+	for len(rest) > 0 && len(ra.usernames) < maxUsers && strings.HasPrefix(rest[0], "@") && len(rest[0]) > 1 {
+		ra.usernames = append(ra.usernames, strings.ToLower(rest[0][1:]))
+		rest = rest[1:]
+	}
+	ra.reason = strings.Join(rest, " ")
+	return
+}
+
+// reassignHandler moves one review: /reassign mr [@from] [@to] [reason]
+func (a *App) reassignHandler(update tgbotapi.Update) error {
+	ra, err := parseReassignArgs(update.Message.CommandArguments(), 2)
+	if err != nil {
+		return err
+	}
+	var from, to string
+	if len(ra.usernames) > 0 {
+		from = ra.usernames[0]
+	}
+	if len(ra.usernames) > 1 {
+		to = ra.usernames[1]
+	}
+	return a.reassign(update, ra.mr, from, to, ra.reason)
+}
+
+// swapHandler hands sender's review over: /swap mr [@to] [reason]
+func (a *App) swapHandler(update tgbotapi.Update) error {
+	ra, err := parseReassignArgs(update.Message.CommandArguments(), 1)
+	if err != nil {
+		return err
+	}
+	var to string
+	if len(ra.usernames) > 0 {
+		to = ra.usernames[0]
+	}
+	return a.reassign(update, ra.mr, "", to, ra.reason)
+}
+
+// reassign moves review of the user (the sender if from is empty) to another user,
+// the best candidate is picked if to is empty
+func (a *App) reassign(update tgbotapi.Update, mrArg, from, to, reason string) error {
+	mr, err := a.mrByArg(mrArg)
+	if err != nil {
+		return err
+	}
+	if mr.IsClosed {
+		return errMrClosed
+	}
+
+	senderID := strconv.Itoa(update.Message.From.ID)
+	var fromUser models.User
+	if from == "" {
+		if fromUser, err = a.DB.GetUserByTgID(senderID); err != nil {
+			return errNotRegistered
+		}
+	} else if fromUser, err = a.userByUsername(from); err != nil {
+		return err
+	}
+	r, err := a.DB.GetReview(mr.ID, fromUser.ID)
+	if err != nil {
+		if from == "" {
+			return errNotReviewer
+		}
+		return newUserError(templates.ErrNotReviewer, from)
+	}
+	if r.IsApproved {
+		return errReviewApproved
+	}
+
+	rc := models.ReviewChange{MrID: mr.ID, FromUserID: fromUser.ID, Reason: reason, ChangedBy: senderID}
+	if to == "" {
+		candidate, err := a.DB.GetUserForReallocateMR(fromUser.UserBrief, mr.ID)
+		if err != nil {
+			return newUserError(templates.ErrNoReviewers, "")
+		}
+		rc.ToUserID = candidate.ID
+		return a.moveReview(rc, candidate.UserBrief)
+	}
+
+	toUser, err := a.userByUsername(to)
+	if err != nil {
+		return err
+	}
+	if !toUser.IsActive {
+		return newUserError(templates.ErrUserInactive, to)
+	}
+	if mr.AuthorID != nil && *mr.AuthorID == toUser.ID {
+		return newUserError(templates.ErrUserIsAuthor, to)
+	}
+	if _, err = a.DB.GetReview(mr.ID, toUser.ID); err == nil {
+		return newUserError(templates.ErrAlreadyReviewer, to)
+	}
+	rc.ToUserID = toUser.ID
+	return a.moveReview(rc, toUser.UserBrief)
+}
+
+// mrByArg finds merge request by url or gitlab id
+func (a *App) mrByArg(arg string) (models.MR, error) {
+	gitlabID, err := strconv.Atoi(strings.TrimPrefix(arg, "!"))
+	if err != nil {
+		if gitlabID, err = models.GetGitlabID(arg); err != nil {
+			return models.MR{}, newUserError(templates.ErrInvalidMR, arg)
+		}
+	}
+	mr, err := a.DB.GetMrByGitlabID(gitlabID)
+	if err != nil {
+		return mr, newUserError(templates.ErrMrNotOnReview, arg)
+	}
+	return mr, nil
+}
@@ -0,0 +1,30 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReassignArgs(t *testing.T) {
+	tests := []struct {
+		args     string
+		maxUsers int
+		exp      reassignArgs
+		expErr   error
+	}{
+		{"", 2, reassignArgs{}, errReassignUsage},
+		{"42", 2, reassignArgs{mr: "42"}, nil},
+		{"42 @Alice @bob On vacation", 2, reassignArgs{mr: "42", usernames: []string{"alice", "bob"}, reason: "On vacation"}, nil},
+		{"42 @alice @bob @carol", 2, reassignArgs{mr: "42", usernames: []string{"alice", "bob"}, reason: "@carol"}, nil},
+		{"42 @alice @bob", 1, reassignArgs{mr: "42", usernames: []string{"alice"}, reason: "@bob"}, nil},
+		{"42 busy with @alice", 2, reassignArgs{mr: "42", reason: "busy with @alice"}, nil},
+	}
+
+	for index, item := range tests {
+		ra, err := parseReassignArgs(item.args, item.maxUsers)
+		if err != item.expErr || (err == nil && !assert.Equal(t, item.exp, ra)) {
+			t.Fatalf("failed at index %d", index)
+		}
+	}
+}
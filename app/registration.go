@@ -0,0 +1,129 @@
+package app
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	ce "tgj-bot/custom_errors"
+	"tgj-bot/models"
+	"tgj-bot/templates"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+const (
+	verificationCodePrefix = "tgj-"
+	defaultCodeTTL         = time.Hour
+)
+
+var (
+	errNoPendingRegistration = newUserError(templates.ErrNoPendingRegistration, "")
+	errCodeExpired           = newUserError(templates.ErrCodeExpired, "")
+	errCodeNotFound          = newUserError(templates.ErrCodeNotFound, "")
+)
+
+// RegistrationConfig enables proof of gitlab account ownership. User posts one-time code
+// as a comment on the issue, or as gitlab status if issue is not set
+type RegistrationConfig struct {
+	IsVerify bool `json:"is_verify"`
+	IssueIID int  `json:"issue_iid"`
+	// issue link shown in instructions
+	IssueURL string       `json:"issue_url"`
+	CodeTTL  JSONDuration `json:"code_ttl"`
+}
+
+func (c RegistrationConfig) codeTTL() time.Duration {
+	if c.CodeTTL == 0 {
+		return defaultCodeTTL
+	}
+	return time.Duration(c.CodeTTL)
+}
+
+// requestVerification issues one-time code, user is saved only after the code is found in gitlab
+func (a *App) requestVerification(user models.User) error {
+	code, err := newVerificationCode()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	ttl := a.Config.Registration.codeTTL()
+	r := models.PendingRegistration{
+		TelegramID:       user.TelegramID,
+		TelegramUsername: user.TelegramUsername,
+		GitlabID:         user.GitlabID,
+		GitlabName:       user.GitlabName,
+		Role:             user.Role,
+		Code:             code,
+		ExpiresAt:        now.Add(ttl).Unix(),
+		CreatedAt:        now.Unix(),
+	}
+	if err = a.DB.SavePendingRegistration(r); err != nil {
+		return err
+	}
+
+	data := verificationData{
+		GitlabName: user.GitlabName,
+		Code:       code,
+		IssueURL:   a.Config.Registration.IssueURL,
+		Minutes:    int(ttl.Minutes()),
+	}
+	if a.Config.Registration.IssueIID == 0 {
+		data.IssueURL = ""
+	}
+	a.sendMessage(a.text(templates.RegisterVerify, data))
+	return nil
+}
+
+func (a *App) verifyHandler(update tgbotapi.Update) error {
+	tgID := strconv.Itoa(update.Message.From.ID)
+	r, err := a.DB.GetPendingRegistration(tgID)
+	if err != nil {
+		return errNoPendingRegistration
+	}
+	if time.Now().Unix() > r.ExpiresAt {
+		return errCodeExpired
+	}
+
+	var isFound bool
+	if a.Config.Registration.IssueIID != 0 {
+		isFound, err = a.Gitlab.HasIssueNote(a.Config.Registration.IssueIID, r.GitlabID, r.Code)
+	} else {
+		isFound, err = a.Gitlab.HasUserStatus(r.GitlabID, r.Code)
+	}
+	if err != nil {
+		return ce.WrapWithLog(err, "verify registration")
+	}
+	if !isFound {
+		return errCodeNotFound
+	}
+
+	user := models.User{
+		UserBrief: models.UserBrief{
+			TelegramID:       r.TelegramID,
+			TelegramUsername: strings.ToLower(update.Message.From.UserName),
+			Role:             r.Role,
+			GitlabID:         r.GitlabID,
+			GitlabName:       r.GitlabName,
+		},
+		IsActive: true,
+	}
+	if _, err = a.DB.SaveUser(user); err != nil {
+		return err
+	}
+	if err = a.DB.DeletePendingRegistration(tgID); err != nil {
+		return err
+	}
+	a.sendMessage(a.text(templates.Success, nil))
+	return nil
+}
+
+func newVerificationCode() (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", ce.Wrap(err, "generate verification code")
+	}
+	return verificationCodePrefix + hex.EncodeToString(b), nil
+}
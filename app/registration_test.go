@@ -0,0 +1,29 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewVerificationCode(t *testing.T) {
+	code, err := newVerificationCode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(code, verificationCodePrefix) || len(code) != len(verificationCodePrefix)+8 {
+		t.Fatalf("unexpected code %q", code)
+	}
+	if other, _ := newVerificationCode(); other == code {
+		t.Fatal("codes must be random")
+	}
+}
+
+func TestRegistrationConfig_codeTTL(t *testing.T) {
+	if ttl := (RegistrationConfig{}).codeTTL(); ttl != defaultCodeTTL {
+		t.Fatalf("unexpected default ttl %v", ttl)
+	}
+	if ttl := (RegistrationConfig{CodeTTL: JSONDuration(time.Minute)}).codeTTL(); ttl != time.Minute {
+		t.Fatalf("unexpected ttl %v", ttl)
+	}
+}
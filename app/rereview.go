@@ -0,0 +1,110 @@
+package app
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	ce "tgj-bot/custom_errors"
+	"tgj-bot/models"
+	"tgj-bot/templates"
+)
+
+// which approvals are reset when new commits are pushed to mr
+const (
+	resetApprovalsOff     = "off"
+	resetApprovalsAll     = "all"
+	resetApprovalsTouched = "touched"
+)
+
+// ReReviewConfig asks reviewers to look at mr again after new commits
+type ReReviewConfig struct {
+	// all, touched - only reviewers with diff threads on changed files, or off by default
+	ResetApprovals string `json:"reset_approvals"`
+}
+
+// checkNewCommits remembers head commit of mr, approvals are reset by config when it changes
+func (a *App) checkNewCommits(mr models.MR, sha string) error {
+	if sha == "" || sha == mr.HeadSHA {
+		return nil
+	}
+	if err := a.DB.UpdateMrHead(mr.ID, sha); err != nil {
+		return err
+	}
+	mode := a.Config.ReReview.ResetApprovals
+	// head is unknown for mrs added before it was tracked
+	if mr.HeadSHA == "" || (mode != resetApprovalsAll && mode != resetApprovalsTouched) {
+		return nil
+	}
+
+	reviewers, err := a.DB.GetReviewersByMrID(mr.ID)
+	if err != nil {
+		return err
+	}
+	var approved []models.Reviewer
+	for _, r := range reviewers {
+		if r.IsApproved {
+			approved = append(approved, r)
+		}
+	}
+	if len(approved) > 0 && mode == resetApprovalsTouched {
+		approved, err = a.touchedByCommits(mr, sha, approved)
+		if err != nil {
+			return err
+		}
+	}
+	if len(approved) == 0 {
+		return nil
+	}
+
+	now := time.Now().Unix()
+	data := reReviewData{URL: mr.URL, Title: mrTitle(mr)}
+	for _, r := range approved {
+		if err = a.DB.ResetReviewApprove(models.Review{MrID: mr.ID, UserID: r.ID, UpdatedAt: now, ResetAt: now}); err != nil {
+			return err
+		}
+		data.Mentions = append(data.Mentions, mention(r.UserBrief))
+	}
+	if mr.IsReviewed {
+		if err = a.DB.ResetMrReviewed(mr.ID); err != nil {
+			return err
+		}
+		if err = a.Gitlab.RemoveLabelFromMR(mr.GitlabID, models.ReviewedLabel); err != nil {
+			log.Println(ce.Wrap(err, "remove reviewed label"))
+		}
+	}
+	a.sendMessageOnce(fmt.Sprintf("rereview:%d:%s", mr.ID, sha), a.text(templates.ReReview, data))
+	return nil
+}
+
+// touchedByCommits returns reviewers whose threads are on files changed since the previous head,
+// all of them if history was rewritten and previous head is gone
+func (a *App) touchedByCommits(mr models.MR, sha string, reviewers []models.Reviewer) ([]models.Reviewer, error) {
+	changed, err := a.Gitlab.ChangedFiles(mr.HeadSHA, sha)
+	if err != nil {
+		log.Println(ce.Wrap(err, "compare mr commits"))
+		return reviewers, nil
+	}
+	threads, err := a.Gitlab.ThreadFiles(mr.GitlabID)
+	if err != nil {
+		return nil, err
+	}
+	return touchedReviewers(reviewers, threads, changed), nil
+}
+
+// touchedReviewers returns reviewers who have threads on the changed files
+func touchedReviewers(reviewers []models.Reviewer, threads map[int][]string, changed []string) (touched []models.Reviewer) {
+	isChanged := make(map[string]bool, len(changed))
+	for _, f := range changed {
+		isChanged[f] = true
+	}
+	for _, r := range reviewers {
+		for _, f := range threads[r.GitlabID] {
+			if f != "" && isChanged[f] {
+				touched = append(touched, r)
+				break
+			}
+		}
+	}
+	return
+}
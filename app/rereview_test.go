@@ -0,0 +1,36 @@
+package app
+
+import (
+	"testing"
+
+	"tgj-bot/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTouchedReviewers(t *testing.T) {
+	alice := models.Reviewer{UserBrief: models.UserBrief{ID: 1, GitlabID: 10}}
+	bob := models.Reviewer{UserBrief: models.UserBrief{ID: 2, GitlabID: 20}}
+	reviewers := []models.Reviewer{alice, bob}
+	threads := map[int][]string{
+		10: {"app/server.go", "app/server.go"},
+		20: {"README.md", ""},
+		30: {"app/router.go", "app/router.go"},
+	}
+
+	tests := []struct {
+		changed []string
+		exp     []models.Reviewer
+	}{
+		{nil, nil},
+		{[]string{"app/server.go"}, []models.Reviewer{alice}},
+		{[]string{"README.md", "app/server.go"}, []models.Reviewer{alice, bob}},
+		{[]string{"app/router.go", ""}, nil},
+	}
+
+	for index, item := range tests {
+		if !assert.Equal(t, item.exp, touchedReviewers(reviewers, threads, item.changed)) {
+			t.Fatalf("failed at index %d", index)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package app
+
+import (
+	"log"
+	"runtime/debug"
+	"time"
+
+	ce "tgj-bot/custom_errors"
+	tg "tgj-bot/external_service/telegram"
+	"tgj-bot/models"
+	"tgj-bot/templates"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+type command string
+
+const (
+	helpCmd       = command("help")
+	registerCmd   = command("register")
+	inactiveCmd   = command("inactive")
+	activeCmd     = command("active")
+	mrCmd         = command("mr")
+	dailyCmd      = command("daily")
+	settingsCmd   = command("settings")
+	startCmd      = command("start")
+	statsCmd      = command("stats")
+	verifyCmd     = command("verify")
+	usersCmd      = command("users")
+	whoisCmd      = command("whois")
+	roleCmd       = command("role")
+	unregisterCmd = command("unregister")
+	reassignCmd   = command("reassign")
+	swapCmd       = command("swap")
+	queueCmd      = command("queue")
+)
+
+// chat scope where command is available
+type scope int
+
+const (
+	scopeGroup = scope(1 << iota)
+	scopePrivate
+	scopeAll = scopeGroup | scopePrivate
+)
+
+// access is a role required to run command
+type access int
+
+const (
+	accessAnyone = access(iota)
+	accessRegistered
+	accessAdmin
+)
+
+// commandSpec describes command for dispatching, help and telegram menu
+type commandSpec struct {
+	name   command
+	args   string
+	access access
+	scope  scope
+	// additional check of arguments, e.g. only admins can change other users
+	permission permission
+	handle     func(a *App, update tgbotapi.Update) error
+}
+
+// commandContext is passed through middleware to the command handler
+type commandContext struct {
+	update tgbotapi.Update
+	spec   commandSpec
+	caller models.User
+}
+
+type commandHandler func(ctx *commandContext) error
+
+type middleware func(next commandHandler) commandHandler
+
+type router struct {
+	// in order of help
+	commands   []commandSpec
+	middleware []middleware
+}
+
+// commands returns registry of bot commands
+func (a *App) commands() *router {
+	r := &router{}
+	r.use(recoverMiddleware, logMiddleware, a.accessMiddleware)
+
+	r.add(commandSpec{name: helpCmd, scope: scopeAll,
+		handle: (*App).helpHandler})
+	r.add(commandSpec{name: registerCmd, args: "gitlab_id [role=dev]", scope: scopeGroup,
+		permission: canRegister, handle: (*App).registerHandler})
+	r.add(commandSpec{name: verifyCmd, scope: scopeGroup,
+		handle: (*App).verifyHandler})
+	r.add(commandSpec{name: mrCmd, args: "merge_request_url [@user] [-@user] [+dev|+lead]", scope: scopeGroup,
+		access: accessRegistered, handle: (*App).mrHandler})
+	r.add(commandSpec{name: reassignCmd, args: "merge_request [@from] [@to] [reason]", scope: scopeGroup,
+		access: accessRegistered, permission: ownReviewOrAdmin, handle: (*App).reassignHandler})
+	r.add(commandSpec{name: swapCmd, args: "merge_request [@to] [reason]", scope: scopeGroup,
+		access: accessRegistered, handle: (*App).swapHandler})
+	r.add(commandSpec{name: inactiveCmd, args: "[username]", scope: scopeGroup,
+		access: accessRegistered, permission: selfOrAdmin, handle: func(a *App, update tgbotapi.Update) error {
+			return a.isActiveHandler(update, false)
+		}})
+	r.add(commandSpec{name: activeCmd, args: "[username]", scope: scopeGroup,
+		access: accessRegistered, permission: selfOrAdmin, handle: func(a *App, update tgbotapi.Update) error {
+			return a.isActiveHandler(update, true)
+		}})
+	r.add(commandSpec{name: statsCmd, scope: scopeGroup,
+		access: accessRegistered, handle: func(a *App, _ tgbotapi.Update) error {
+			return a.statsHandler()
+		}})
+	r.add(commandSpec{name: queueCmd, scope: scopeGroup,
+		access: accessRegistered, handle: func(a *App, _ tgbotapi.Update) error {
+			return a.queueHandler()
+		}})
+	r.add(commandSpec{name: usersCmd, scope: scopeGroup,
+		access: accessRegistered, handle: func(a *App, _ tgbotapi.Update) error {
+			return a.usersHandler()
+		}})
+	r.add(commandSpec{name: whoisCmd, args: "username", scope: scopeGroup,
+		access: accessRegistered, handle: (*App).whoisHandler})
+	r.add(commandSpec{name: roleCmd, args: "username dev|lead", scope: scopeGroup,
+		access: accessAdmin, handle: (*App).roleHandler})
+	r.add(commandSpec{name: unregisterCmd, args: "[username]", scope: scopeGroup,
+		access: accessRegistered, permission: selfOrAdmin, handle: (*App).unregisterHandler})
+	r.add(commandSpec{name: settingsCmd, args: "[notify group|dm|both] [quiet from-to|off]", scope: scopeAll,
+		access: accessRegistered, handle: (*App).settingsHandler})
+	r.add(commandSpec{name: startCmd, scope: scopePrivate,
+		handle: (*App).startHandler})
+	r.add(commandSpec{name: dailyCmd, scope: scopeGroup,
+		access: accessAdmin, handle: func(a *App, _ tgbotapi.Update) error {
+			if !a.Config.Notifier.IsAllowBotCMD {
+				return errFeatureUnavailable
+			}
+			return a.sendDailyNotification("")
+		}})
+	return r
+}
+
+// descriptionTemplate names template with description of the command for help and menu
+func (spec commandSpec) descriptionTemplate() string {
+	return templates.CommandPrefix + string(spec.name)
+}
+
+func (r *router) add(spec commandSpec) {
+	r.commands = append(r.commands, spec)
+}
+
+func (r *router) use(m ...middleware) {
+	r.middleware = append(r.middleware, m...)
+}
+
+// lookup returns command available in the chat scope
+func (r *router) lookup(name command, s scope) (commandSpec, bool) {
+	for _, spec := range r.commands {
+		if spec.name == name && spec.scope&s != 0 {
+			return spec, true
+		}
+	}
+	return commandSpec{}, false
+}
+
+// available returns commands of the chat scope
+func (r *router) available(s scope) (specs []commandSpec) {
+	for _, spec := range r.commands {
+		if spec.scope&s != 0 {
+			specs = append(specs, spec)
+		}
+	}
+	return
+}
+
+// dispatch runs command through middleware, unknown commands show help
+func (a *App) dispatch(update tgbotapi.Update, s scope) error {
+	spec, ok := a.router.lookup(command(update.Message.Command()), s)
+	if !ok {
+		spec, _ = a.router.lookup(helpCmd, s)
+	}
+
+	h := func(ctx *commandContext) error {
+		return ctx.spec.handle(a, ctx.update)
+	}
+	for i := len(a.router.middleware) - 1; i >= 0; i-- {
+		h = a.router.middleware[i](h)
+	}
+	return h(&commandContext{update: update, spec: spec})
+}
+
+func recoverMiddleware(next commandHandler) commandHandler {
+	return func(ctx *commandContext) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("command /%s panic: %v\n%s", ctx.spec.name, r, debug.Stack())
+				err = newUserError(templates.ErrCommandFailed, string(ctx.spec.name))
+			}
+		}()
+		return next(ctx)
+	}
+}
+
+func logMiddleware(next commandHandler) commandHandler {
+	return func(ctx *commandContext) error {
+		start := time.Now()
+		err := next(ctx)
+		log.Printf("command /%s from %s args=%q took %v err=%v", ctx.spec.name, ctx.update.Message.From.UserName,
+			ctx.update.Message.CommandArguments(), time.Since(start), err)
+		return err
+	}
+}
+
+// accessMiddleware checks registration, role and arguments of the command, denials are audited
+func (a *App) accessMiddleware(next commandHandler) commandHandler {
+	return func(ctx *commandContext) error {
+		if ctx.spec.access == accessAnyone && ctx.spec.permission == nil {
+			return next(ctx)
+		}
+
+		ctx.caller = a.caller(ctx.update.Message.From)
+		if ctx.spec.access == accessRegistered && ctx.caller.ID == 0 {
+			return errNotRegistered
+		}
+		if err := a.checkPermission(ctx); err != nil {
+			return err
+		}
+		return next(ctx)
+	}
+}
+
+func (a *App) helpHandler(update tgbotapi.Update) error {
+	// help is posted to the team chat unless it is asked in private chat
+	s, chatID := scopeGroup, a.Config.Tg.ChatID
+	if chat := update.Message.Chat; chat != nil && chat.IsPrivate() {
+		s, chatID = scopePrivate, chat.ID
+	}
+	var lines []helpLine
+	for _, spec := range a.router.available(s) {
+		lines = append(lines, helpLine{
+			Name:        string(spec.name),
+			Args:        spec.args,
+			Description: a.textFor(chatID, spec.descriptionTemplate(), nil),
+			IsAdmin:     spec.access == accessAdmin,
+		})
+	}
+	a.reply(update, a.textFor(chatID, templates.Help, lines))
+	return nil
+}
+
+// setBotCommands fills telegram menu of commands for group and private chats
+func (a *App) setBotCommands() {
+	for s, tgScope := range map[scope]string{scopeGroup: tg.ScopeGroupChats, scopePrivate: tg.ScopePrivateChats} {
+		var commands []tg.BotCommand
+		for _, spec := range a.router.available(s) {
+			description := a.text(spec.descriptionTemplate(), nil)
+			if spec.args != "" {
+				description = spec.args + " - " + description
+			}
+			commands = append(commands, tg.BotCommand{Command: string(spec.name), Description: description})
+		}
+		if err := a.Telegram.SetCommands(commands, tgScope); err != nil {
+			log.Println(ce.Wrap(err, "set bot commands "+tgScope))
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package app
+
+import (
+	"errors"
+	"testing"
+
+	ce "tgj-bot/custom_errors"
+	"tgj-bot/templates"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+func TestRouter_lookup(t *testing.T) {
+	r := (&App{}).commands()
+	tests := []struct {
+		cmd   command
+		scope scope
+		isOk  bool
+	}{
+		{helpCmd, scopeGroup, true},
+		{helpCmd, scopePrivate, true},
+		{mrCmd, scopeGroup, true},
+		{mrCmd, scopePrivate, false},
+		{startCmd, scopePrivate, true},
+		{startCmd, scopeGroup, false},
+		{settingsCmd, scopePrivate, true},
+		{command("unknown"), scopeGroup, false},
+	}
+
+	for index, item := range tests {
+		if _, ok := r.lookup(item.cmd, item.scope); ok != item.isOk {
+			t.Fatalf("failed at index %d", index)
+		}
+	}
+}
+
+func TestRouter_commandsAreDescribed(t *testing.T) {
+	tmpl, err := templates.New(templates.Config{Chats: map[int64]string{1: templates.Russian}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &App{Templates: tmpl}
+	for _, spec := range a.commands().commands {
+		// english and russian chats
+		for _, chatID := range []int64{0, 1} {
+			if a.textFor(chatID, spec.descriptionTemplate(), nil) == "" || spec.scope == 0 || spec.handle == nil {
+				t.Fatalf("command /%s is not fully described", spec.name)
+			}
+		}
+	}
+}
+
+func TestRouter_middleware(t *testing.T) {
+	a := &App{router: &router{}}
+	var calls []string
+	trace := func(name string) middleware {
+		return func(next commandHandler) commandHandler {
+			return func(ctx *commandContext) error {
+				calls = append(calls, name)
+				return next(ctx)
+			}
+		}
+	}
+	a.router.use(recoverMiddleware, trace("first"), trace("second"))
+	a.router.add(commandSpec{name: helpCmd, scope: scopeAll, handle: func(*App, tgbotapi.Update) error {
+		panic("boom")
+	}})
+
+	update := tgbotapi.Update{Message: &tgbotapi.Message{Text: "/unknown", Entities: &[]tgbotapi.MessageEntity{{Type: "bot_command", Length: 8}}}}
+	err := a.dispatch(update, scopeGroup)
+	if err == nil {
+		t.Fatal("panic must be turned into error")
+	}
+	if len(calls) != 2 || calls[0] != "first" || calls[1] != "second" {
+		t.Fatalf("unexpected middleware order %v", calls)
+	}
+}
+
+func TestErrorText(t *testing.T) {
+	tmpl, err := templates.New(templates.Config{Chats: map[int64]string{1: templates.Russian}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &App{Templates: tmpl}
+
+	testCases := []struct {
+		chatID int64
+		err    error
+		text   string
+	}{
+		{0, errMrClosed, "merge request is already closed"},
+		{1, errMrClosed, "merge request уже закрыт"},
+		{0, newUserError(templates.ErrUserInactive, "<bob>"), "@&lt;bob&gt; is inactive"},
+		{1, ce.ErrNotReviewer, "вы не ревьюер этого merge request"},
+		{0, errors.New("<db> failed"), "&lt;db&gt; failed"},
+	}
+
+	for i, tc := range testCases {
+		if text := a.errorText(tc.chatID, tc.err); text != tc.text {
+			t.Fatalf("failed at index %d: %q", i, text)
+		}
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"html"
 	"log"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,6 +16,9 @@ import (
 	"tgj-bot/external_service/jira"
 	tg "tgj-bot/external_service/telegram"
 	"tgj-bot/models"
+	"tgj-bot/templates"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api"
 )
 
 type Config struct {
@@ -24,6 +29,17 @@ type Config struct {
 	Notifier NotifierConfig  `json:"notifier"`
 	Jira     jira.Config     `json:"jira"`
 	Timings  TimingsConf     `json:"timings"`
+	// escalation of stalled reviews
+	Escalation EscalationConfig `json:"escalation"`
+	// message templates and languages
+	Templates templates.Config `json:"templates"`
+	// telegram ids of admins, granted on start to registered users
+	Admins       []int64            `json:"admins"`
+	Registration RegistrationConfig `json:"registration"`
+	ReReview     ReReviewConfig     `json:"re_review"`
+	Pipeline     PipelineConfig     `json:"pipeline"`
+	Merge        MergeConfig        `json:"merge"`
+	Stale        StaleConfig        `json:"stale"`
 }
 
 type ReviewParty struct {
@@ -32,104 +48,180 @@ type ReviewParty struct {
 }
 
 type NotifierConfig struct {
-	IsAllow       bool     `json:"is_allow"`
-	IsAllowBotCMD bool     `json:"is_allow_bot_cmd"`
-	TimeHour      int      `json:"time_hour"`
-	TimeMinute    int      `json:"time_minute"`
-	Delay         int64    `json:"delay"`
-	Praise        []string `json:"praise"`
-	Motivate      []string `json:"motivate"`
+	IsAllow       bool  `json:"is_allow"`
+	IsAllowBotCMD bool  `json:"is_allow_bot_cmd"`
+	TimeHour      int   `json:"time_hour"`
+	TimeMinute    int   `json:"time_minute"`
+	Delay         int64 `json:"delay"`
 }
 
 type TimingsConf struct {
 	UpdateGitlabStatePeriod JSONDuration `json:"update_gitlab_state"`
 	UpdateJiraTasksPeriod   JSONDuration `json:"update_jira_tasks"`
 	CheckNotifyPeriod       JSONDuration `json:"check_notify"`
+	CheckEscalationPeriod   JSONDuration `json:"check_escalation"`
+	DeliverOutboxPeriod     JSONDuration `json:"deliver_outbox"`
+	// closed mrs are checked to be reopened during the window
+	ReopenWindow JSONDuration `json:"reopen_window"`
 }
 
-type App struct {
-	Telegram tg.Client
-	Gitlab   gl.Client
-	DB       db.Client
-	Config   Config
-	Jira     *jira.Jira
+const (
+	defaultReopenWindow          = 7 * 24 * time.Hour
+	defaultCheckEscalationPeriod = 10 * time.Minute
+	defaultDeliverOutboxPeriod   = 5 * time.Second
+)
+
+func (t TimingsConf) reopenWindow() time.Duration {
+	if t.ReopenWindow == 0 {
+		return defaultReopenWindow
+	}
+	return time.Duration(t.ReopenWindow)
 }
 
-type command string
+// checkEscalationPeriod is used by configs made before escalations, zero period would stop the ticker
+func (t TimingsConf) checkEscalationPeriod() time.Duration {
+	if t.CheckEscalationPeriod == 0 {
+		return defaultCheckEscalationPeriod
+	}
+	return time.Duration(t.CheckEscalationPeriod)
+}
 
-const (
-	helpCmd     = command("help")
-	registerCmd = command("register")
-	inactiveCmd = command("inactive")
-	activeCmd   = command("active")
-	mrCmd       = command("mr")
-	dailyCmd    = command("daily")
-)
+// deliverOutboxPeriod is used by configs made before outbox, zero period would stop the ticker
+func (t TimingsConf) deliverOutboxPeriod() time.Duration {
+	if t.DeliverOutboxPeriod == 0 {
+		return defaultDeliverOutboxPeriod
+	}
+	return time.Duration(t.DeliverOutboxPeriod)
+}
 
-const success = "Success! 👍"
+type App struct {
+	Telegram  tg.Client
+	Gitlab    gl.Client
+	DB        db.Client
+	Config    Config
+	Jira      *jira.Jira
+	Templates *templates.Templates
+
+	router *router
+	// known usernames by telegram id, to save changed ones only
+	usernames map[string]string
+}
 
 func (a *App) Serve() (err error) {
 	if err := a.migrateData(); err != nil {
 		return err
 	}
+	if err := a.bootstrapAdmins(); err != nil {
+		return err
+	}
+	a.router = a.commands()
+	a.setBotCommands()
+	a.deliverOutbox()
 	a.notify()
 	a.updateTasksFromJira()
 	a.updateStateFromGitlab()
+	a.escalateReviews()
 
-	for update := range a.Telegram.Updates {
-		if update.Message == nil {
-			continue
-		}
-		if update.Message.Chat != nil {
-			if update.Message.Chat.ID != a.Config.Tg.ChatID {
-				continue
-			}
-		}
-		tgUsername := update.Message.From.UserName
-		if !update.Message.IsCommand() {
-			continue
-		}
-		switch command(update.Message.Command()) {
-		case helpCmd:
-			err = a.helpHandler()
-		case registerCmd:
-			err = a.registerHandler(update)
-		case inactiveCmd:
-			if _, err = a.isUserRegister(tgUsername); err == nil {
-				err = a.isActiveHandler(update, false)
-			}
-		case activeCmd:
-			if _, err = a.isUserRegister(tgUsername); err == nil {
-				err = a.isActiveHandler(update, true)
-			}
-		case mrCmd:
-			if _, err = a.isUserRegister(tgUsername); err == nil {
-				err = a.mrHandler(update)
-			}
-		case dailyCmd:
-			if a.Config.Notifier.IsAllowBotCMD {
-				err = a.sendDailyNotification()
-			} else {
-				err = errors.New("this feature not available")
+	// telegram allows only one consumer of updates per bot token, other replicas wait in standby
+	lock := a.DB.NewLock(jobUpdates)
+	for !a.isLeader(lock) {
+		time.Sleep(updatesLeaderPeriod)
+	}
+	if err = a.Telegram.StartUpdates(); err != nil {
+		return err
+	}
+	log.Println("polling telegram updates")
+	check := time.NewTicker(updatesLeaderPeriod)
+	defer check.Stop()
+	for {
+		select {
+		case update := <-a.Telegram.Updates:
+			a.serveUpdate(update)
+		case <-check.C:
+			// replica is restarted as standby, so two replicas never poll at once for long
+			if !a.isLeader(lock) {
+				a.Telegram.StopUpdates()
+				return errors.New("lock of telegram updates is lost")
 			}
-		default:
-			err = a.helpHandler()
 		}
+	}
+}
 
-		if err != nil {
-			log.Print(err)
-			a.Telegram.SendMessage(err.Error())
+// serveUpdate handles commands of the team chat, private chats and callbacks of buttons
+func (a *App) serveUpdate(update tgbotapi.Update) {
+	a.refreshUsername(update)
+	if update.CallbackQuery != nil {
+		a.serveCallback(update.CallbackQuery)
+		return
+	}
+	if update.Message == nil {
+		return
+	}
+	if update.Message.Chat != nil && update.Message.Chat.IsPrivate() {
+		a.servePrivate(update)
+		return
+	}
+	if update.Message.Chat != nil {
+		if update.Message.Chat.ID != a.Config.Tg.ChatID {
+			return
 		}
 	}
-	return
+	if !update.Message.IsCommand() {
+		return
+	}
+	if err := a.dispatch(update, scopeGroup); err != nil {
+		log.Print(err)
+		a.sendMessage(a.errorText(a.Config.Tg.ChatID, err))
+	}
 }
 
-func (a *App) isUserRegister(tgUsername string) (int, error) {
-	u, err := a.DB.GetUserByTgUsername(strings.ToLower(tgUsername))
-	if err != nil {
-		err = ce.WrapWithLog(err, ce.ErrUserNorRegistered.Error())
+// servePrivate handles personal commands sent to the bot in private chat
+func (a *App) servePrivate(update tgbotapi.Update) {
+	if !update.Message.IsCommand() {
+		return
+	}
+
+	if err := a.dispatch(update, scopePrivate); err != nil {
+		log.Print(err)
+		a.reply(update, a.errorText(update.Message.Chat.ID, err))
+	}
+}
+
+// refreshUsername keeps telegram username of the sender up to date, users are identified by telegram id
+func (a *App) refreshUsername(update tgbotapi.Update) {
+	var from *tgbotapi.User
+	switch {
+	case update.Message != nil:
+		from = update.Message.From
+	case update.CallbackQuery != nil:
+		from = update.CallbackQuery.From
+	}
+	if from == nil {
+		return
+	}
+
+	tgID, username := strconv.Itoa(from.ID), strings.ToLower(from.UserName)
+	if known, ok := a.usernames[tgID]; ok && known == username {
+		return
+	}
+	if err := a.DB.UpdateTelegramUsername(tgID, username); err != nil {
+		log.Println(err)
+		return
+	}
+	if a.usernames == nil {
+		a.usernames = make(map[string]string)
+	}
+	a.usernames[tgID] = username
+}
+
+func (a *App) serveCallback(query *tgbotapi.CallbackQuery) {
+	if query.Message == nil || query.Message.Chat == nil || query.Message.Chat.ID != a.Config.Tg.ChatID {
+		return
+	}
+	if err := a.callbackHandler(query); err != nil {
+		log.Print(err)
+		a.Telegram.AnswerCallback(query.ID, html.UnescapeString(a.errorText(query.Message.Chat.ID, err)))
 	}
-	return int(u.ID), err
 }
 
 func (a *App) updateStateFromGitlab() {
@@ -138,8 +230,12 @@ func (a *App) updateStateFromGitlab() {
 		return
 	}
 
+	lock := a.DB.NewLock(jobGitlabState)
 	go func() {
 		for range time.Tick(time.Duration(a.Config.Timings.UpdateGitlabStatePeriod)) {
+			if !a.isLeader(lock) {
+				continue
+			}
 			log.Println("update state from gitlab...")
 			if err := a.updateReviews(); err != nil {
 				log.Println(ce.Wrap(err, "notifier update reviews"))
@@ -154,8 +250,12 @@ func (a *App) updateTasksFromJira() {
 		log.Println("skip updating tasks from jira")
 		return
 	}
+	lock := a.DB.NewLock(jobJiraTasks)
 	go func() {
 		for range time.Tick(time.Duration(a.Config.Timings.UpdateJiraTasksPeriod)) {
+			if !a.isLeader(lock) {
+				continue
+			}
 			ctx := context.Background()
 			log.Println("updating mrs info from jira...")
 			mrs, err := a.DB.GetAllMRs()
@@ -181,6 +281,7 @@ func (a *App) updateTaskFromJira(ctx context.Context, mr models.MR) error {
 			return err
 		}
 
+		mr.Title = title
 		mr.ExtractJiraID(title)
 		isChanged = true
 	}
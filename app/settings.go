@@ -0,0 +1,154 @@
+package app
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	ce "tgj-bot/custom_errors"
+	"tgj-bot/models"
+	"tgj-bot/templates"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+const quietOff = "off"
+
+var errSettingsUsage = newUserError(templates.ErrSettingsUsage, "")
+
+func (a *App) startHandler(update tgbotapi.Update) (err error) {
+	tgID := strconv.Itoa(update.Message.From.ID)
+	if _, err = a.DB.GetUserByTgID(tgID); err != nil {
+		return errNotRegistered
+	}
+	if err = a.DB.SetPrivateChat(tgID, true); err != nil {
+		return
+	}
+	a.reply(update, a.textFor(update.Message.Chat.ID, templates.StartDone, nil))
+	return
+}
+
+func (a *App) settingsHandler(update tgbotapi.Update) (err error) {
+	u, err := a.DB.GetUserByTgID(strconv.Itoa(update.Message.From.ID))
+	if err != nil {
+		return errNotRegistered
+	}
+	s, err := a.DB.GetUserSettings(u.ID)
+	if err != nil {
+		return
+	}
+
+	args := strings.Fields(strings.ToLower(update.Message.CommandArguments()))
+	if len(args) == 0 {
+		a.reply(update, a.textFor(update.Message.Chat.ID, templates.Settings, newSettingsData(s)))
+		return
+	}
+	if len(args) != 2 {
+		return errSettingsUsage
+	}
+
+	switch args[0] {
+	case "notify":
+		mode := models.NotifyMode(args[1])
+		if !models.IsValidNotifyMode(mode) {
+			return newUserError(templates.ErrInvalidNotifyMode, fmt.Sprint(models.ValidNotifyModes))
+		}
+		s.NotifyMode = mode
+	case "quiet":
+		if s.QuietFrom, s.QuietTo, err = parseQuietHours(args[1]); err != nil {
+			return
+		}
+	default:
+		return errSettingsUsage
+	}
+
+	if err = a.DB.SaveUserSettings(s); err != nil {
+		return
+	}
+	a.reply(update, a.textFor(update.Message.Chat.ID, templates.Settings, newSettingsData(s)))
+	return
+}
+
+// userDelivery returns where user's reminders should be sent and when direct message is delivered,
+// team chat is used on any doubt
+func (a *App) userDelivery(u models.User, t time.Time) (group, dm bool, dmAt time.Time) {
+	s, err := a.DB.GetUserSettings(u.ID)
+	if err != nil {
+		a.logError(err)
+		return true, false, time.Time{}
+	}
+	return s.Delivery(t)
+}
+
+// sendDirectMessage renders message in language of user's private chat and sends it there,
+// message deferred by quiet hours waits in the outbox
+func (a *App) sendDirectMessage(u models.User, dedupKey, name string, data interface{}, at time.Time) error {
+	chatID, err := strconv.ParseInt(u.TelegramID, 10, 64)
+	if err != nil {
+		return ce.Wrap(err, fmt.Sprintf("invalid telegram id of %s", u.TelegramUsername))
+	}
+	msg := a.textFor(chatID, name, data)
+	if at.After(time.Now()) {
+		_, err = a.enqueue(chatID, dedupKey, msg, nil, at)
+		return err
+	}
+	return a.send(chatID, dedupKey, msg, nil)
+}
+
+// notifyUser sends message to private chat or team chat as the user chose in /settings
+func (a *App) notifyUser(u models.User, dedupKey, name string, data interface{}) {
+	group, dm, dmAt := a.userDelivery(u, time.Now())
+	if dm {
+		if err := a.sendDirectMessage(u, dedupKey, name, data, dmAt); err != nil {
+			log.Println(ce.Wrap(err, name+" direct message"))
+			group = true
+		}
+	}
+	if group {
+		a.sendMessageOnce(dedupKey, a.text(name, data))
+	}
+}
+
+// reply sends message to the chat where command came from
+func (a *App) reply(update tgbotapi.Update, msg string) {
+	if update.Message.Chat != nil && update.Message.Chat.IsPrivate() {
+		if err := a.send(update.Message.Chat.ID, "", msg, nil); err != nil {
+			log.Println(ce.Wrap(err, "reply"))
+		}
+		return
+	}
+	a.sendMessage(msg)
+}
+
+func newSettingsData(s models.UserSettings) settingsData {
+	data := settingsData{
+		NotifyMode:     string(s.NotifyMode),
+		HasPrivateChat: s.HasPrivateChat,
+	}
+	if s.QuietFrom != s.QuietTo {
+		data.Quiet = fmt.Sprintf("%d-%d", s.QuietFrom, s.QuietTo)
+	}
+	return data
+}
+
+// parseQuietHours parses hours range like 22-8 or off
+func parseQuietHours(arg string) (from, to int, err error) {
+	if arg == quietOff {
+		return 0, 0, nil
+	}
+	invalidArgument := newUserError(templates.ErrInvalidQuietHours, arg)
+
+	hours := strings.Split(arg, "-")
+	if len(hours) != 2 {
+		return 0, 0, invalidArgument
+	}
+	if from, err = strconv.Atoi(hours[0]); err != nil || from < 0 || from > 23 {
+		return 0, 0, invalidArgument
+	}
+	if to, err = strconv.Atoi(hours[1]); err != nil || to < 0 || to > 23 {
+		return 0, 0, invalidArgument
+	}
+	return from, to, nil
+}
@@ -0,0 +1,30 @@
+package app
+
+import "testing"
+
+func TestParseQuietHours(t *testing.T) {
+	tests := []struct {
+		arg     string
+		expFrom int
+		expTo   int
+		isErr   bool
+	}{
+		{"22-8", 22, 8, false},
+		{"0-23", 0, 23, false},
+		{"off", 0, 0, false},
+		{"22", 0, 0, true},
+		{"22-24", 0, 0, true},
+		{"-1-8", 0, 0, true},
+		{"a-b", 0, 0, true},
+	}
+
+	for index, item := range tests {
+		from, to, err := parseQuietHours(item.arg)
+		if (err != nil) != item.isErr {
+			t.Fatalf("failed at index %d: unexpected err %v", index, err)
+		}
+		if from != item.expFrom || to != item.expTo {
+			t.Fatalf("failed at index %d", index)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package app
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	ce "tgj-bot/custom_errors"
+	gl "tgj-bot/external_service/gitlab"
+	"tgj-bot/models"
+	"tgj-bot/templates"
+)
+
+const staleEmoji = "💤"
+
+// StaleConfig detects mrs abandoned by authors, thresholds are in business days, zero disables the rule.
+// Stale mrs are not counted in payload of reviewers and they are not reminded about them
+type StaleConfig struct {
+	IsAllow bool `json:"is_allow"`
+	// no commits and notes
+	InactiveDays float64 `json:"inactive_days"`
+	// threads wait for reply of author
+	UnansweredDays float64 `json:"unanswered_days"`
+	// label is added to mr which is stale for label_days
+	Label     string  `json:"label"`
+	LabelDays float64 `json:"label_days"`
+	// mr which is stale for close_days is closed in gitlab
+	CloseDays float64 `json:"close_days"`
+}
+
+// staleDays returns business days since mr is abandoned, zero if it is active.
+// lastActivity is unix time of the latest commit or note, unanswered are last notes of threads waiting for author
+func (c StaleConfig) staleDays(lastActivity int64, unanswered []int64, now time.Time) (days float64) {
+	if c.InactiveDays > 0 && lastActivity > 0 {
+		if d := businessHours(time.Unix(lastActivity, 0), now) / 24; d >= c.InactiveDays {
+			days = d
+		}
+	}
+	if c.UnansweredDays > 0 {
+		for _, at := range unanswered {
+			if d := businessHours(time.Unix(at, 0), now) / 24; d >= c.UnansweredDays && d > days {
+				days = d
+			}
+		}
+	}
+	return
+}
+
+// checkStale marks abandoned mr and nudges author, mr is labeled or closed later by config.
+// Stale mr is active again after new commits or replies
+func (a *App) checkStale(mr models.MR, gitlabMR *gl.GitlabMR, threads []models.Thread) error {
+	cfg := a.Config.Stale
+	if !cfg.IsAllow {
+		return nil
+	}
+
+	lastActivity, err := a.headCommitTime(mr, gitlabMR.SHA)
+	if err != nil {
+		return err
+	}
+	var unanswered []int64
+	for _, t := range threads {
+		if t.LastNoteAt > lastActivity {
+			lastActivity = t.LastNoteAt
+		}
+		if t.AwaitingID != 0 && t.AwaitingID == gitlabMR.AuthorID {
+			unanswered = append(unanswered, t.LastNoteAt)
+		}
+	}
+
+	now := time.Now()
+	days := cfg.staleDays(lastActivity, unanswered, now)
+	if days == 0 {
+		if mr.IsStale() {
+			return a.reviveMR(mr)
+		}
+		return nil
+	}
+
+	if !mr.IsStale() {
+		mr.StaleAt = now.Unix()
+		if err := a.DB.UpdateMrStale(mr.ID, mr.StaleAt); err != nil {
+			return err
+		}
+		log.Printf("mr_id=%d is stale for %.1f days", mr.ID, days)
+		if err := a.nudgeAuthor(mr, days); err != nil {
+			log.Println(ce.Wrap(err, "nudge author of stale mr"))
+		}
+	}
+
+	switch {
+	case cfg.CloseDays > 0 && days >= cfg.CloseDays:
+		log.Printf("close stale mr_id=%d", mr.ID)
+		return a.Gitlab.CloseMR(mr.GitlabID)
+	case cfg.Label != "" && cfg.LabelDays > 0 && days >= cfg.LabelDays && !mr.IsStaleLabeled:
+		if err := a.Gitlab.AddLabelToMR(mr.GitlabID, cfg.Label); err != nil {
+			return err
+		}
+		return a.DB.MarkMrStaleLabeled(mr.ID)
+	}
+	return nil
+}
+
+// headCommitTime returns commit time of mr head, it is requested from gitlab only when head changes
+func (a *App) headCommitTime(mr models.MR, sha string) (int64, error) {
+	if sha == "" {
+		return 0, nil
+	}
+	if sha == mr.HeadSHA && mr.HeadCommittedAt != 0 {
+		return mr.HeadCommittedAt, nil
+	}
+	at, err := a.Gitlab.CommitTime(sha)
+	if err != nil {
+		return 0, err
+	}
+	if err = a.DB.UpdateMrHeadCommitTime(mr.ID, sha, at); err != nil {
+		log.Println(ce.Wrap(err, "cache head commit time"))
+	}
+	return at, nil
+}
+
+// reviveMR returns stale mr to review, waiting time of reviewers starts over
+func (a *App) reviveMR(mr models.MR) error {
+	if err := a.DB.UpdateMrStale(mr.ID, 0); err != nil {
+		return err
+	}
+	if err := a.DB.ResetReviewsTime(mr.ID, time.Now().Unix()); err != nil {
+		return err
+	}
+	log.Printf("stale mr_id=%d is active again", mr.ID)
+	if a.Config.Stale.Label != "" {
+		return a.Gitlab.RemoveLabelFromMR(mr.GitlabID, a.Config.Stale.Label)
+	}
+	return nil
+}
+
+func (a *App) nudgeAuthor(mr models.MR, days float64) error {
+	if mr.AuthorID == nil || *mr.AuthorID == 0 {
+		return nil
+	}
+	author, err := a.DB.GetUserByID(*mr.AuthorID)
+	if err != nil {
+		return err
+	}
+	data := staleData{
+		Mention:   mention(author.UserBrief),
+		Days:      int(days),
+		CloseDays: int(a.Config.Stale.CloseDays),
+		URL:       mr.URL,
+		Title:     mrTitle(mr),
+	}
+	a.notifyUser(author, fmt.Sprintf("stale:%d:%d", mr.ID, mr.StaleAt), templates.StaleNudge, data)
+	return nil
+}
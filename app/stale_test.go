@@ -0,0 +1,39 @@
+package app
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStaleConfig_StaleDays(t *testing.T) {
+	// friday noon
+	now := time.Date(2019, 10, 4, 12, 0, 0, 0, time.Local)
+	daysAgo := func(days int) int64 {
+		return now.AddDate(0, 0, -days).Unix()
+	}
+	cfg := StaleConfig{InactiveDays: 5, UnansweredDays: 3}
+
+	tests := []struct {
+		cfg          StaleConfig
+		lastActivity int64
+		unanswered   []int64
+		exp          float64
+	}{
+		{cfg, daysAgo(1), nil, 0},
+		// weekend is skipped
+		{cfg, daysAgo(6), nil, 0},
+		{cfg, daysAgo(7), nil, 5},
+		{cfg, daysAgo(1), []int64{daysAgo(2)}, 0},
+		{cfg, daysAgo(1), []int64{daysAgo(2), daysAgo(3)}, 3},
+		{cfg, daysAgo(7), []int64{daysAgo(3)}, 5},
+		{StaleConfig{}, daysAgo(30), []int64{daysAgo(30)}, 0},
+		// activity is unknown
+		{cfg, 0, nil, 0},
+	}
+
+	for index, item := range tests {
+		if value := item.cfg.staleDays(item.lastActivity, item.unanswered, now); value != item.exp {
+			t.Fatalf("failed at index %d: got %v", index, value)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package app
+
+import (
+	"fmt"
+
+	"tgj-bot/models"
+	"tgj-bot/templates"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+const (
+	approvedEmoji  = "✅"
+	commentedEmoji = "💬"
+	waitingEmoji   = "⏳"
+	// reviewer took mr in work
+	takenEmoji = "🛠"
+)
+
+// statusDedupKey is dedup key of live status message of the mr in the outbox
+func statusDedupKey(mrID int) string {
+	return fmt.Sprintf("status:%d", mrID)
+}
+
+// updateMrStatus edits chat message of the mr with actual reviews state
+func (a *App) updateMrStatus(mr models.MR, state string) error {
+	if mr.MessageID == 0 {
+		var err error
+		if mr.MessageID, err = a.statusMessageID(mr); err != nil || mr.MessageID == 0 {
+			return err
+		}
+	}
+
+	reviewers, err := a.DB.GetReviewersByMrID(mr.ID)
+	if err != nil {
+		return err
+	}
+
+	var keyboard *tgbotapi.InlineKeyboardMarkup
+	if state == models.StateOpened && !isReviewed(reviewers) {
+		k := a.reviewKeyboard(mr)
+		keyboard = &k
+	}
+	return a.edit(mr.MessageID, a.renderMrStatus(mr, state, reviewers), keyboard)
+}
+
+// statusMessageID links mr with its status message once outbox delivered it,
+// zero id means the message is not sent yet or mr was created before live status messages
+func (a *App) statusMessageID(mr models.MR) (int, error) {
+	m, err := a.DB.GetMessageByDedupKey(statusDedupKey(mr.ID))
+	if err != nil || m.Status != models.OutboxSent {
+		return 0, err
+	}
+	if err = a.DB.UpdateMrMessage(mr.ID, m.MessageID); err != nil {
+		return 0, err
+	}
+	return m.MessageID, nil
+}
+
+func (a *App) renderMrStatus(mr models.MR, state string, reviewers []models.Reviewer) string {
+	data := mrStatusData{
+		URL:        mr.URL,
+		Title:      mrTitle(mr),
+		State:      state,
+		IsReviewed: isReviewed(reviewers),
+	}
+	for _, r := range reviewers {
+		data.Reviewers = append(data.Reviewers, reviewerLine{Emoji: reviewerStatusEmoji(r), Username: displayName(r.UserBrief), Mention: mention(r.UserBrief)})
+	}
+	return a.text(templates.MrStatus, data)
+}
+
+func reviewerStatusEmoji(r models.Reviewer) string {
+	switch {
+	case r.IsApproved:
+		return approvedEmoji
+	case r.IsCommented:
+		return commentedEmoji
+	case r.IsTaken:
+		return takenEmoji
+	default:
+		return waitingEmoji
+	}
+}
+
+func isReviewed(reviewers []models.Reviewer) bool {
+	if len(reviewers) == 0 {
+		return false
+	}
+	for _, r := range reviewers {
+		if !r.IsApproved {
+			return false
+		}
+	}
+	return true
+}
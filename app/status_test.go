@@ -0,0 +1,59 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"tgj-bot/models"
+	"tgj-bot/templates"
+)
+
+func TestRenderMrStatus(t *testing.T) {
+	tmpl, err := templates.New(templates.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := App{Templates: tmpl}
+
+	mr := models.MR{URL: "https://gitlab/group/project/merge_requests/1"}
+	reviewers := []models.Reviewer{
+		{UserBrief: models.UserBrief{TelegramUsername: "alice"}, IsApproved: true},
+		{UserBrief: models.UserBrief{TelegramUsername: "bob"}, IsCommented: true},
+		{UserBrief: models.UserBrief{TelegramUsername: "carol"}},
+		{UserBrief: models.UserBrief{TelegramID: "42", GitlabName: "dave"}},
+		{UserBrief: models.UserBrief{TelegramUsername: "erin"}, IsTaken: true},
+	}
+
+	msg := a.renderMrStatus(mr, models.StateOpened, reviewers)
+	for _, line := range []string{approvedEmoji + " @alice", commentedEmoji + " @bob", waitingEmoji + " @carol", `<a href="tg://user?id=42">dave</a>`, takenEmoji + " @erin", mr.URL} {
+		if !strings.Contains(msg, line) {
+			t.Fatalf("line %q not found in %q", line, msg)
+		}
+	}
+	if strings.Contains(msg, "reviewed") {
+		t.Fatalf("mr must not be reviewed: %q", msg)
+	}
+
+	msg = a.renderMrStatus(mr, models.StateMerged, reviewers)
+	if !strings.HasSuffix(msg, "merged") {
+		t.Fatalf("merged state not found in %q", msg)
+	}
+}
+
+func TestIsReviewed(t *testing.T) {
+	tests := []struct {
+		reviewers []models.Reviewer
+		exp       bool
+	}{
+		{nil, false},
+		{[]models.Reviewer{{IsApproved: true}}, true},
+		{[]models.Reviewer{{IsApproved: true}, {IsCommented: true}}, false},
+		{[]models.Reviewer{{IsApproved: true}, {IsApproved: true}}, true},
+	}
+
+	for index, item := range tests {
+		if isReviewed(item.reviewers) != item.exp {
+			t.Fatalf("failed at index %d", index)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package app
+
+import (
+	"fmt"
+	"math/rand"
+
+	tg "tgj-bot/external_service/telegram"
+	"tgj-bot/models"
+)
+
+// template data of outgoing messages
+
+type reviewerLine struct {
+	Emoji string
+	// plain name, e.g. to list users without notifying them
+	Username string
+	// rendered HTML mention
+	Mention string
+}
+
+type mrLine struct {
+	Emoji string
+	URL   string
+	Title string
+}
+
+type mrStatusData struct {
+	Reviewers  []reviewerLine
+	URL        string
+	Title      string
+	State      string
+	IsReviewed bool
+}
+
+// queueLine is open mr with display names of reviewers who have not approved it yet
+type queueLine struct {
+	Emoji   string
+	URL     string
+	Title   string
+	Waiting []string
+}
+
+// readyToMergeData tells author that all reviewers approved mr
+type readyToMergeData struct {
+	Mention      string
+	URL          string
+	Title        string
+	HasConflicts bool
+	IsAutoMerge  bool
+}
+
+// staleData nudges author of abandoned mr
+type staleData struct {
+	Mention string
+	Days    int
+	// mr is closed automatically after the days, zero if it is not
+	CloseDays int
+	URL       string
+	Title     string
+}
+
+type userMrData struct {
+	Mention string
+	URL     string
+	Title   string
+}
+
+type dailyData struct {
+	Mention string
+	Emoji   string
+	Tasks   []mrLine
+	Reviews []mrLine
+	// threads waiting for reply of the user
+	Threads []mrLine
+}
+
+type settingsData struct {
+	NotifyMode     string
+	Quiet          string
+	HasPrivateChat bool
+}
+
+type escalationData struct {
+	Mention string
+	Leads   []string
+	Hours   int
+	URL     string
+	Title   string
+}
+
+type verificationData struct {
+	GitlabName string
+	Code       string
+	IssueURL   string
+	Minutes    int
+}
+
+type helpLine struct {
+	Name        string
+	Args        string
+	Description string
+	IsAdmin     bool
+}
+
+// reReviewData asks reviewers to look at mr again after new commits
+type reReviewData struct {
+	Mentions []string
+	URL      string
+	Title    string
+}
+
+type whoisData struct {
+	Mention    string
+	GitlabName string
+	GitlabID   int
+	Role       string
+	IsActive   bool
+	IsAdmin    bool
+	Reviews    int
+}
+
+type tableData struct {
+	Rows [][]string
+}
+
+// mention renders user mention for the message, by telegram id if user has no username
+func mention(u models.UserBrief) string {
+	return tg.Mention(u.TelegramUsername, u.TelegramID, u.GitlabName)
+}
+
+// displayName is a plain name of user which does not notify them
+func displayName(u models.UserBrief) string {
+	if u.TelegramUsername != "" {
+		return u.TelegramUsername
+	}
+	return u.GitlabName
+}
+
+// mrTitle is a text of MR link: "!123 Fix login [NC-42]", url is shown when title is unknown
+func mrTitle(mr models.MR) string {
+	if mr.Title == "" {
+		return ""
+	}
+	return fmt.Sprintf("!%d %s", mr.GitlabID, mr.Title)
+}
+
+// text renders message for the team chat
+func (a *App) text(name string, data interface{}) string {
+	return a.Templates.Render(a.Config.Tg.ChatID, name, data)
+}
+
+// textFor renders message in language of the chat
+func (a *App) textFor(chatID int64, name string, data interface{}) string {
+	return a.Templates.Render(chatID, name, data)
+}
+
+// randText returns random line of the template for the team chat
+func (a *App) randText(name string) string {
+	vs := a.Templates.Variants(a.Config.Tg.ChatID, name)
+	if len(vs) == 0 {
+		return ""
+	}
+	return vs[rand.Intn(len(vs))]
+}
@@ -0,0 +1,128 @@
+package app
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"tgj-bot/models"
+	"tgj-bot/templates"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+var (
+	errWhoisUsage = newUserError(templates.ErrWhoisUsage, "")
+	errRoleUsage  = newUserError(templates.ErrRoleUsage, "")
+)
+
+func (a *App) usersHandler() error {
+	us, err := a.DB.GetUsers()
+	if err != nil {
+		return err
+	}
+	data := tableData{Rows: [][]string{{"user", "gitlab", "role", "status"}}}
+	for _, u := range us {
+		status := "active"
+		if !u.IsActive {
+			status = "inactive"
+		}
+		if u.IsAdmin {
+			status += ", admin"
+		}
+		data.Rows = append(data.Rows, []string{displayName(u.UserBrief), u.GitlabName, string(u.Role), status})
+	}
+	a.sendMessage(a.text(templates.Users, data))
+	return nil
+}
+
+func (a *App) whoisHandler(update tgbotapi.Update) error {
+	args := strings.Fields(strings.ToLower(update.Message.CommandArguments()))
+	if len(args) != 1 {
+		return errWhoisUsage
+	}
+	u, err := a.userByUsername(args[0])
+	if err != nil {
+		return err
+	}
+	rs, err := a.DB.GetOpenedReviewsByUserID(u.ID)
+	if err != nil {
+		return err
+	}
+	data := whoisData{
+		Mention:    mention(u.UserBrief),
+		GitlabName: u.GitlabName,
+		GitlabID:   u.GitlabID,
+		Role:       string(u.Role),
+		IsActive:   u.IsActive,
+		IsAdmin:    u.IsAdmin,
+		Reviews:    len(rs),
+	}
+	a.sendMessage(a.text(templates.Whois, data))
+	return nil
+}
+
+func (a *App) roleHandler(update tgbotapi.Update) error {
+	args := strings.Fields(strings.ToLower(update.Message.CommandArguments()))
+	if len(args) != 2 {
+		return errRoleUsage
+	}
+	role := models.Role(args[1])
+	if !models.IsValidRole(role) {
+		return newUserError(templates.ErrInvalidRole, fmt.Sprint(models.ValidRoles))
+	}
+	u, err := a.userByUsername(args[0])
+	if err != nil {
+		return err
+	}
+	if u.Role != role {
+		if err = a.DB.ChangeUserRole(u.ID, role); err != nil {
+			return err
+		}
+	}
+	a.sendMessage(a.text(templates.Success, nil))
+	return nil
+}
+
+// unregisterHandler removes user from review, open reviews are reallocated first.
+// User is kept while any review can't be moved, so mr is not left without reviewer
+func (a *App) unregisterHandler(update tgbotapi.Update) error {
+	u, err := a.targetUser(update)
+	if err != nil {
+		return err
+	}
+	failed, err := a.reallocateUserMRs(u, reasonUnregister)
+	if err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		return newUserError(templates.ErrUnregisterReviews, strings.Join(failed, ", "))
+	}
+	if err = a.DB.DeleteUser(u.ID); err != nil {
+		return err
+	}
+	a.sendMessage(a.text(templates.Success, nil))
+	return nil
+}
+
+// targetUser returns user from the first argument of the command or the sender, who may have no username
+func (a *App) targetUser(update tgbotapi.Update) (models.User, error) {
+	args := strings.Fields(update.Message.CommandArguments())
+	if len(args) > 0 {
+		return a.userByUsername(args[0])
+	}
+	u, err := a.DB.GetUserByTgID(strconv.Itoa(update.Message.From.ID))
+	if err != nil {
+		return u, errNotRegistered
+	}
+	return u, nil
+}
+
+// userByUsername returns registered user by telegram username with or without @
+func (a *App) userByUsername(username string) (models.User, error) {
+	u, err := a.DB.GetUserByTgUsername(strings.ToLower(strings.TrimPrefix(username, "@")))
+	if err != nil {
+		return u, newUserError(templates.ErrNotRegistered, strings.TrimPrefix(username, "@"))
+	}
+	return u, nil
+}
@@ -12,6 +12,7 @@ import (
 	gitlab_ "tgj-bot/external_service/gitlab"
 	"tgj-bot/external_service/jira"
 	"tgj-bot/external_service/telegram"
+	"tgj-bot/templates"
 )
 
 func main() {
@@ -31,6 +32,11 @@ func main() {
 		log.Panic(err)
 	}
 
+	app.Templates, err = templates.New(app.Config.Templates)
+	if err != nil {
+		log.Panic(err)
+	}
+
 	app.Telegram, err = telegram.RunBot(app.Config.Tg)
 	if err != nil {
 		log.Panic(err)
@@ -17,6 +17,7 @@ var (
 	ErrCloseMRs               = errors.New("close merge request error")
 	ErrInvalidVariableType    = errors.New("invalid variable type")
 	ErrGetUsersWithPayload    = errors.New("users with payload not found")
+	ErrNotReviewer            = errors.New("you are not a reviewer of this merge request")
 )
 
 func Wrap(err error, msg string) error {
@@ -0,0 +1 @@
+ALTER TABLE reviews ADD COLUMN IF NOT EXISTS is_taken BOOLEAN NOT NULL DEFAULT FALSE;
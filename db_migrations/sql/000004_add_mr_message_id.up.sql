@@ -0,0 +1 @@
+ALTER TABLE mrs ADD COLUMN IF NOT EXISTS message_id INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,4 @@
+ALTER TABLE users DROP COLUMN IF EXISTS notify_mode;
+ALTER TABLE users DROP COLUMN IF EXISTS quiet_from;
+ALTER TABLE users DROP COLUMN IF EXISTS quiet_to;
+ALTER TABLE users DROP COLUMN IF EXISTS has_private_chat;
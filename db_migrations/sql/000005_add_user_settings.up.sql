@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS notify_mode TEXT NOT NULL DEFAULT 'group';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS quiet_from INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS quiet_to INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS has_private_chat BOOLEAN NOT NULL DEFAULT FALSE;
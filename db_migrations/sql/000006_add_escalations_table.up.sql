@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS escalations (
+    mr_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    step INTEGER NOT NULL,
+    stalled_since BIGINT NOT NULL,
+    created_at BIGINT NOT NULL,
+    PRIMARY KEY (mr_id, user_id, step, stalled_since),
+    FOREIGN KEY(mr_id) REFERENCES mrs(id),
+    FOREIGN KEY(user_id) REFERENCES users(id)
+);
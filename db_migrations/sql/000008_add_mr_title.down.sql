@@ -0,0 +1 @@
+ALTER TABLE mrs DROP COLUMN IF EXISTS title;
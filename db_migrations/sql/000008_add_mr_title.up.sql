@@ -0,0 +1 @@
+ALTER TABLE mrs ADD COLUMN IF NOT EXISTS title TEXT NOT NULL DEFAULT '';
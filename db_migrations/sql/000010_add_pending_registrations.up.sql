@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS pending_registrations (
+    telegram_id TEXT PRIMARY KEY,
+    telegram_username TEXT NOT NULL,
+    gitlab_id INTEGER NOT NULL,
+    gitlab_name TEXT NOT NULL,
+    role TEXT NOT NULL,
+    code TEXT NOT NULL,
+    expires_at BIGINT NOT NULL,
+    created_at BIGINT NOT NULL
+);
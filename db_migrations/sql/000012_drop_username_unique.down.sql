@@ -0,0 +1,4 @@
+ALTER TABLE users ALTER COLUMN telegram_username DROP NOT NULL;
+ALTER TABLE users ALTER COLUMN telegram_username DROP DEFAULT;
+UPDATE users SET telegram_username = NULL WHERE telegram_username = '';
+ALTER TABLE users ADD CONSTRAINT users_telegram_username_key UNIQUE (telegram_username);
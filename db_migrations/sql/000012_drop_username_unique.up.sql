@@ -0,0 +1,5 @@
+-- users are identified by telegram_id, username is optional and may move to another account
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_telegram_username_key;
+UPDATE users SET telegram_username = '' WHERE telegram_username IS NULL;
+ALTER TABLE users ALTER COLUMN telegram_username SET DEFAULT '';
+ALTER TABLE users ALTER COLUMN telegram_username SET NOT NULL;
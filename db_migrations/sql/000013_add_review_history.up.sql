@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS review_history (
+    id SERIAL PRIMARY KEY,
+    mr_id INT NOT NULL REFERENCES mrs (id),
+    from_user_id INT NOT NULL REFERENCES users (id),
+    to_user_id INT NOT NULL REFERENCES users (id),
+    reason TEXT NOT NULL DEFAULT '',
+    -- telegram id of user who moved the review, empty if moved by the bot
+    changed_by TEXT NOT NULL DEFAULT '',
+    created_at BIGINT NOT NULL
+);
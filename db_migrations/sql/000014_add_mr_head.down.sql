@@ -0,0 +1,4 @@
+ALTER TABLE reviews DROP COLUMN IF EXISTS reset_at;
+UPDATE mrs SET is_closed = TRUE WHERE is_reviewed = TRUE;
+ALTER TABLE mrs DROP COLUMN IF EXISTS head_sha;
+ALTER TABLE mrs DROP COLUMN IF EXISTS is_reviewed;
@@ -0,0 +1,5 @@
+-- mr stays open after review until it is merged or closed in gitlab, new commits may reset approvals
+ALTER TABLE mrs ADD COLUMN IF NOT EXISTS is_reviewed BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE mrs ADD COLUMN IF NOT EXISTS head_sha TEXT NOT NULL DEFAULT '';
+UPDATE mrs SET is_reviewed = is_closed;
+ALTER TABLE reviews ADD COLUMN IF NOT EXISTS reset_at BIGINT NOT NULL DEFAULT 0;
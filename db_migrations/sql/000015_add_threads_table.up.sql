@@ -0,0 +1,14 @@
+-- gitlab discussions of mrs, users are gitlab ids
+CREATE TABLE IF NOT EXISTS threads (
+    mr_id INT NOT NULL REFERENCES mrs (id),
+    discussion_id TEXT NOT NULL,
+    note_id INT NOT NULL,
+    opened_by INT NOT NULL,
+    last_note_by INT NOT NULL,
+    is_resolvable BOOLEAN NOT NULL DEFAULT FALSE,
+    is_resolved BOOLEAN NOT NULL DEFAULT FALSE,
+    -- user expected to reply, 0 if nobody
+    awaiting_id INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (mr_id, discussion_id)
+);
+CREATE INDEX IF NOT EXISTS threads_awaiting_id_idx ON threads (awaiting_id) WHERE awaiting_id != 0;
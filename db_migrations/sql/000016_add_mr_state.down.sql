@@ -0,0 +1,3 @@
+ALTER TABLE mrs DROP COLUMN IF EXISTS closed_at;
+ALTER TABLE mrs DROP COLUMN IF EXISTS merged_at;
+ALTER TABLE mrs DROP COLUMN IF EXISTS state;
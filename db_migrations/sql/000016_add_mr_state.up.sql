@@ -0,0 +1,6 @@
+-- gitlab state of mr, mr closed without merge is not a successful review
+ALTER TABLE mrs ADD COLUMN IF NOT EXISTS state TEXT NOT NULL DEFAULT 'opened';
+ALTER TABLE mrs ADD COLUMN IF NOT EXISTS merged_at BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE mrs ADD COLUMN IF NOT EXISTS closed_at BIGINT NOT NULL DEFAULT 0;
+-- earlier mrs were closed after review
+UPDATE mrs SET state = 'merged' WHERE is_closed = TRUE;
@@ -0,0 +1,2 @@
+ALTER TABLE mrs DROP COLUMN IF EXISTS pending_args;
+ALTER TABLE mrs DROP COLUMN IF EXISTS is_pending;
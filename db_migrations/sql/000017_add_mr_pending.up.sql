@@ -0,0 +1,3 @@
+-- draft mr waits to be ready for review, reviewers are assigned later with saved /mr arguments
+ALTER TABLE mrs ADD COLUMN IF NOT EXISTS is_pending BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE mrs ADD COLUMN IF NOT EXISTS pending_args TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+-- status of head pipeline of mr, empty if project has no ci
+ALTER TABLE mrs ADD COLUMN IF NOT EXISTS pipeline_status TEXT NOT NULL DEFAULT '';
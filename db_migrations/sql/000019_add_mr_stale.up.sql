@@ -0,0 +1,2 @@
+-- mr abandoned by author, it is not counted in payload of reviewers and they are not reminded about it
+ALTER TABLE mrs ADD COLUMN IF NOT EXISTS stale_at BIGINT NOT NULL DEFAULT 0;
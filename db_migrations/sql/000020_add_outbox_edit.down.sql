@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS outbox_edit_idx;
+DROP INDEX IF EXISTS outbox_message_idx;
+DROP INDEX IF EXISTS outbox_chat_idx;
+ALTER TABLE outbox DROP COLUMN IF EXISTS edit_message_id;
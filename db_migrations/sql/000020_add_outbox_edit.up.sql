@@ -0,0 +1,5 @@
+-- message which replaces text of already sent one, e.g. live status of mr
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS edit_message_id INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS outbox_chat_idx ON outbox (chat_id, id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS outbox_message_idx ON outbox (chat_id, message_id);
+CREATE INDEX IF NOT EXISTS outbox_edit_idx ON outbox (chat_id, edit_message_id) WHERE edit_message_id != 0;
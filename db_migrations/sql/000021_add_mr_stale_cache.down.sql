@@ -0,0 +1,2 @@
+ALTER TABLE mrs DROP COLUMN IF EXISTS is_stale_labeled;
+ALTER TABLE mrs DROP COLUMN IF EXISTS head_committed_at;
@@ -0,0 +1,4 @@
+-- commit time of head_sha, it is requested from gitlab only when head changes
+ALTER TABLE mrs ADD COLUMN IF NOT EXISTS head_committed_at BIGINT NOT NULL DEFAULT 0;
+-- stale label is added once, when mr crosses label threshold
+ALTER TABLE mrs ADD COLUMN IF NOT EXISTS is_stale_labeled BOOLEAN NOT NULL DEFAULT FALSE;
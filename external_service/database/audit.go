@@ -0,0 +1,16 @@
+package database
+
+import (
+	ce "tgj-bot/custom_errors"
+	"tgj-bot/models"
+)
+
+func (c *Client) SaveAuditRecord(r models.AuditRecord) (err error) {
+	q := `INSERT INTO audit_log (telegram_id, telegram_username, command, args, reason, created_at)
+		  VALUES ($1, $2, $3, $4, $5, $6)`
+	_, err = c.db.Exec(q, r.TelegramID, r.TelegramUsername, r.Command, r.Args, r.Reason, r.CreatedAt)
+	if err != nil {
+		err = ce.WrapWithLog(err, "save audit record")
+	}
+	return
+}
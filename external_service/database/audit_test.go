@@ -0,0 +1,29 @@
+package database
+
+import (
+	"testing"
+
+	"tgj-bot/models"
+	"tgj-bot/th"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_SaveAuditRecord(t *testing.T) {
+	f := newFixture(t)
+	defer f.finish()
+
+	exp := models.AuditRecord{
+		TelegramID:       th.String(),
+		TelegramUsername: th.String(),
+		Command:          "daily",
+		Reason:           "admins only",
+		CreatedAt:        int64(th.Int()),
+	}
+	assert.NoError(t, f.SaveAuditRecord(exp))
+
+	var act models.AuditRecord
+	q := `SELECT telegram_id, telegram_username, command, args, reason, created_at FROM audit_log`
+	assert.NoError(t, f.db.QueryRow(q).Scan(&act.TelegramID, &act.TelegramUsername, &act.Command, &act.Args, &act.Reason, &act.CreatedAt))
+	assert.Equal(t, exp, act)
+}
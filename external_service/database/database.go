@@ -3,6 +3,7 @@ package database
 import (
 	"database/sql"
 	"fmt"
+	"log"
 
 	ce "tgj-bot/custom_errors"
 	"tgj-bot/models"
@@ -20,22 +21,41 @@ type Database interface {
 
 type UserRepository interface {
 	SaveUser(u models.User) (int, error)
-	ChangeIsActiveUser(telegramUsername string, isActive bool) (err error)
+	ChangeIsActiveUser(uID int, isActive bool) (err error)
+	UpdateTelegramUsername(tgID, tgUsername string) (err error)
 	GetUsersWithPayload(exceptTelegramID string) (ups models.UsersPayload, err error)
 	GetUserByTgUsername(tgUname string) (u models.User, err error)
+	GetUserByTgID(tgID string) (u models.User, err error)
 	GetUserByGitlabID(id interface{}) (u models.User, err error)
 	GetUsersByMrID(id int) (us []models.UserBrief, err error)
 	GetUsersByMrURL(url string) (us []models.UserBrief, err error)
 	GetUserForReallocateMR(u models.UserBrief, mID int) (up models.UserPayload, err error)
 	GetActiveUsers() (us models.UserList, err error)
+	GetUserSettings(uID int) (s models.UserSettings, err error)
+	SaveUserSettings(s models.UserSettings) (err error)
+	SetPrivateChat(tgID string, hasPrivateChat bool) (err error)
+	GrantAdmins(tgIDs []string) (err error)
+	GetUsers() (us models.UserList, err error)
+	ChangeUserRole(uID int, role models.Role) (err error)
+	DeleteUser(uID int) (err error)
 }
 
 type MergeRequestRepository interface {
 	SaveMR(mr models.MR) (models.MR, error)
 	GetOpenedMRs() (mrs []models.MR, err error)
-	CloseMRs() error
+	MarkMRsReviewed() (mrs []models.MR, err error)
+	UpdateMrState(mr models.MR) error
+	GetAbandonedMRs(since int64) (mrs []models.MR, err error)
+	UpdateMrHead(id int, sha string) error
+	UpdateMrHeadCommitTime(id int, sha string, committedAt int64) error
+	MarkMrStaleLabeled(id int) error
+	UpdateMrPipeline(id int, status string) error
+	UpdateMrStale(id int, staleAt int64) error
+	UpdateMrMessage(id, messageID int) error
+	ResetMrReviewed(id int) error
 	GetMrByID(id int) (mr models.MR, err error)
 	GetMRbyURL(url string) (mr models.MR, err error)
+	GetMrByGitlabID(gitlabID int) (mr models.MR, err error)
 	GetAllMRs() ([]models.MR, error)
 }
 
@@ -43,9 +63,47 @@ type ReviewRepository interface {
 	SaveReview(r models.Review) (err error)
 	UpdateReviewApprove(r models.Review) error
 	UpdateReviewComment(r models.Review) (err error)
+	UpdateReviewTaken(r models.Review) (err error)
+	UpdateReviewTime(r models.Review) (err error)
+	ResetReviewsTime(mrID int, updatedAt int64) (err error)
+	GetReview(mrID, uID int) (r models.Review, err error)
+	ResetReviewApprove(r models.Review) (err error)
+	GetReviewersByMrID(mrID int) (rs []models.Reviewer, err error)
 	GetReviewMRsByUserID(uID int) (ids []int, err error)
 	DeleteReview(r models.Review) (err error)
 	GetOpenedReviewsByUserID(uID int) (rs []models.Review, err error)
+	SaveReviewChange(c models.ReviewChange) (err error)
+	GetReviewChanges(mrID int) (cs []models.ReviewChange, err error)
+}
+
+type ThreadRepository interface {
+	SaveThreads(mrID int, ts []models.Thread) (err error)
+	GetAwaitingThreads(gitlabID int) (ts []models.Thread, err error)
+}
+
+type EscalationRepository interface {
+	GetStalledReviews() (rs []models.StalledReview, err error)
+	SaveEscalation(r models.Review, step models.EscalationStep) (err error)
+}
+
+type RegistrationRepository interface {
+	SavePendingRegistration(r models.PendingRegistration) (err error)
+	GetPendingRegistration(tgID string) (r models.PendingRegistration, err error)
+	DeletePendingRegistration(tgID string) (err error)
+}
+
+type AuditRepository interface {
+	SaveAuditRecord(r models.AuditRecord) (err error)
+}
+
+type OutboxRepository interface {
+	EnqueueMessage(m models.OutboxMessage) (id int, err error)
+	ClaimDueMessages(now, leaseUntil, chatID int64, limit int) (ms []models.OutboxMessage, err error)
+	GetMessageByDedupKey(dedupKey string) (m models.OutboxMessage, err error)
+	GetLastMessageVersion(chatID int64, messageID int) (m models.OutboxMessage, err error)
+	MarkMessageSent(id, messageID int, sentAt int64) (err error)
+	MarkMessageRetry(id int, nextAttemptAt int64, lastError string) (err error)
+	MarkMessageFailed(id int, lastError string) (err error)
 }
 
 type DbConfig struct {
@@ -66,17 +124,31 @@ func (c *DbConfig) MigrationDSN() string {
 	return fmt.Sprintf("%s://%s:%s@%s/%s?sslmode=disable", c.DriverName, c.User, c.Pass, c.Host, c.DBName)
 }
 
+// querier is implemented by *sql.DB and *sql.Tx, so the same queries run inside and outside of transaction
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
 type Client struct {
-	db *sql.DB
+	db querier
+	// connection pool, nil for client of transaction
+	conn *sql.DB
+}
+
+func newClient(conn *sql.DB) Client {
+	return Client{db: conn, conn: conn}
 }
 
 func RunDB(cfg DbConfig) (dbClient Client, err error) {
-	dbClient.db, err = sql.Open(cfg.DriverName, cfg.DSN())
+	conn, err := sql.Open(cfg.DriverName, cfg.DSN())
 	if err != nil {
 		err = ce.WrapWithLog(err, "DB client err")
 		return
 	}
-	if err = dbClient.db.Ping(); err != nil {
+	dbClient = newClient(conn)
+	if err = conn.Ping(); err != nil {
 		err = ce.WrapWithLog(err, "DB ping err")
 		return
 	}
@@ -100,5 +172,35 @@ func runMigrations(dsn, path string) error {
 }
 
 func (c *Client) Close() {
-	c.db.Close()
+	if c.conn != nil {
+		c.conn.Close()
+	}
+}
+
+// WithTx runs fn as a unit of work, all queries of tx client are committed if fn returns nil
+// and rolled back otherwise. Called on client of transaction, fn joins that transaction
+func (c *Client) WithTx(fn func(tx *Client) error) (err error) {
+	if c.conn == nil {
+		return fn(c)
+	}
+	sqlTx, err := c.conn.Begin()
+	if err != nil {
+		return ce.WrapWithLog(err, "begin transaction")
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = sqlTx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			if rbErr := sqlTx.Rollback(); rbErr != nil {
+				log.Println(ce.Wrap(rbErr, "rollback transaction"))
+			}
+			return
+		}
+		if err = sqlTx.Commit(); err != nil {
+			err = ce.WrapWithLog(err, "commit transaction")
+		}
+	}()
+	return fn(&Client{db: sqlTx})
 }
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -28,6 +29,47 @@ func TestClient_Dummy(t *testing.T) {
 	f.finish()
 }
 
+func TestClient_WithTx(t *testing.T) {
+	t.Run("should commit", func(t *testing.T) {
+		f := newFixture(t)
+		defer f.finish()
+		u := f.createUser()
+
+		var mr models.MR
+		err := f.WithTx(func(tx *Client) (err error) {
+			mr, err = tx.CreateMR(models.MR{URL: th.String(), AuthorID: &u.ID})
+			return
+		})
+		assert.NoError(t, err)
+		_, err = f.GetMrByID(mr.ID)
+		assert.NoError(t, err)
+	})
+
+	t.Run("should rollback on error", func(t *testing.T) {
+		f := newFixture(t)
+		defer f.finish()
+		u := f.createUser()
+
+		url := th.String()
+		err := f.WithTx(func(tx *Client) error {
+			mr, err := tx.CreateMR(models.MR{URL: url, AuthorID: &u.ID})
+			if err != nil {
+				return err
+			}
+			// nested call joins the transaction
+			return tx.WithTx(func(tx *Client) error {
+				if err := tx.SaveReview(models.Review{MrID: mr.ID, UserID: u.ID}); err != nil {
+					return err
+				}
+				return errors.New("gitlab is unavailable")
+			})
+		})
+		assert.EqualError(t, err, "gitlab is unavailable")
+		_, err = f.GetMRbyURL(url)
+		assert.Error(t, err)
+	})
+}
+
 type fixture struct {
 	Client
 	T *testing.T
@@ -37,10 +79,8 @@ func newFixture(t *testing.T) *fixture {
 	db := fixtures.New(t, conf.DriverName, conf.MigrationDSN()).DB
 	require.NoError(t, db.Ping())
 	f := &fixture{
-		Client: Client{
-			db: db,
-		},
-		T: t,
+		Client: newClient(db),
+		T:      t,
 	}
 
 	require.NoError(t, runMigrations(conf.MigrationDSN(), conf.MigrationsDir))
@@ -122,12 +162,12 @@ func (f *fixture) getMR(url string) models.MR {
 }
 
 func (f *fixture) getMRs(urls ...string) []models.MR {
-	q := `SELECT id, url, author_id, is_closed FROM mrs WHERE url = $1`
+	q := `SELECT id, url, author_id, is_closed, is_reviewed FROM mrs WHERE url = $1`
 	n := len(urls)
 	mrs := make([]models.MR, n, n)
 	mr := models.MR{}
 	for i, url := range urls {
-		assert.NoError(f.T, f.db.QueryRow(q, url).Scan(&mr.ID, &mr.URL, &mr.AuthorID, &mr.IsClosed))
+		assert.NoError(f.T, f.db.QueryRow(q, url).Scan(&mr.ID, &mr.URL, &mr.AuthorID, &mr.IsClosed, &mr.IsReviewed))
 		mrs[i] = mr
 	}
 	return mrs
@@ -154,28 +194,28 @@ func (f *fixture) createReviews(reviews map[int][]int) []models.Review {
 }
 
 func (f *fixture) getReviewsByMR(mrID int) (rs []models.Review) {
-	q := `SELECT mr_id, user_id, is_approved, is_commented, updated_at FROM reviews WHERE mr_id = $1`
+	q := `SELECT mr_id, user_id, is_approved, is_commented, is_taken, updated_at FROM reviews WHERE mr_id = $1`
 	rows, err := f.db.Query(q, mrID)
 	assert.NoError(f.T, err)
 	defer rows.Close()
 
 	var r models.Review
 	for rows.Next() {
-		assert.NoError(f.T, rows.Scan(&r.MrID, &r.UserID, &r.IsApproved, &r.IsCommented, &r.UpdatedAt))
+		assert.NoError(f.T, rows.Scan(&r.MrID, &r.UserID, &r.IsApproved, &r.IsCommented, &r.IsTaken, &r.UpdatedAt))
 		rs = append(rs, r)
 	}
 	return
 }
 
 func (f *fixture) getReviewsByUser(uID int) (rs []models.Review) {
-	q := `SELECT mr_id, user_id, is_approved, is_commented, updated_at FROM reviews WHERE user_id = $1`
+	q := `SELECT mr_id, user_id, is_approved, is_commented, is_taken, updated_at FROM reviews WHERE user_id = $1`
 	rows, err := f.db.Query(q, uID)
 	assert.NoError(f.T, err)
 	defer rows.Close()
 
 	var r models.Review
 	for rows.Next() {
-		assert.NoError(f.T, rows.Scan(&r.MrID, &r.UserID, &r.IsApproved, &r.IsCommented, &r.UpdatedAt))
+		assert.NoError(f.T, rows.Scan(&r.MrID, &r.UserID, &r.IsApproved, &r.IsCommented, &r.IsTaken, &r.UpdatedAt))
 		rs = append(rs, r)
 	}
 	return
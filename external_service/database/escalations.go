@@ -0,0 +1,51 @@
+package database
+
+import (
+	"time"
+
+	ce "tgj-bot/custom_errors"
+	"tgj-bot/models"
+)
+
+// GetStalledReviews returns reviews waiting for reviewer with the last escalation step
+// made since the review was updated
+func (c *Client) GetStalledReviews() (rs []models.StalledReview, err error) {
+	q := `SELECT r.mr_id, r.user_id, r.is_taken, r.updated_at, m.jira_priority, m.pipeline_status,
+				 COALESCE((SELECT max(e.step)
+						   FROM escalations e
+						   WHERE e.mr_id = r.mr_id
+							 AND e.user_id = r.user_id
+							 AND e.stalled_since = r.updated_at), 0) AS step
+		  FROM reviews r
+		  JOIN mrs m on r.mr_id = m.id
+		  WHERE r.is_approved = FALSE
+		    AND r.is_commented = FALSE
+		    AND m.is_closed = FALSE
+		    AND m.stale_at = 0`
+	rows, err := c.db.Query(q)
+	if err != nil {
+		err = ce.WrapWithLog(err, "get stalled reviews")
+		return
+	}
+	defer rows.Close()
+
+	var r models.StalledReview
+	for rows.Next() {
+		if err = rows.Scan(&r.MrID, &r.UserID, &r.IsTaken, &r.UpdatedAt, &r.JiraPriority, &r.PipelineStatus, &r.Step); err != nil {
+			err = ce.WrapWithLog(err, "get stalled reviews scan")
+			return
+		}
+		rs = append(rs, r)
+	}
+	return
+}
+
+func (c *Client) SaveEscalation(r models.Review, step models.EscalationStep) (err error) {
+	q := `INSERT INTO escalations (mr_id, user_id, step, stalled_since, created_at) VALUES ($1, $2, $3, $4, $5)
+		  ON CONFLICT DO NOTHING`
+	_, err = c.db.Exec(q, r.MrID, r.UserID, step, r.UpdatedAt, time.Now().Unix())
+	if err != nil {
+		err = ce.WrapWithLog(err, "save escalation")
+	}
+	return
+}
@@ -0,0 +1,93 @@
+package database
+
+import (
+	"testing"
+
+	"tgj-bot/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_GetStalledReviews(t *testing.T) {
+	t.Run("should return not escalated review", func(t *testing.T) {
+		f := newFixture(t)
+		defer f.finish()
+		u := f.createUsersN(2)
+		m := f.createMR(u[0].ID)
+
+		reviews := make(map[int][]int)
+		reviews[u[1].ID] = []int{m.ID}
+		r := f.createReviews(reviews)[0]
+
+		rs, err := f.GetStalledReviews()
+		assert.NoError(t, err)
+		assert.Len(t, rs, 1)
+		assert.Equal(t, models.StalledReview{Review: r, Step: models.EscalationNone}, rs[0])
+	})
+	t.Run("should return last escalation step", func(t *testing.T) {
+		f := newFixture(t)
+		defer f.finish()
+		u := f.createUsersN(2)
+		m := f.createMR(u[0].ID)
+
+		reviews := make(map[int][]int)
+		reviews[u[1].ID] = []int{m.ID}
+		r := f.createReviews(reviews)[0]
+
+		assert.NoError(t, f.SaveEscalation(r, models.EscalationReminder))
+		assert.NoError(t, f.SaveEscalation(r, models.EscalationLead))
+		// repeated step is ignored
+		assert.NoError(t, f.SaveEscalation(r, models.EscalationLead))
+
+		rs, err := f.GetStalledReviews()
+		assert.NoError(t, err)
+		assert.Len(t, rs, 1)
+		assert.Equal(t, models.EscalationLead, rs[0].Step)
+	})
+	t.Run("should keep stall time while threads are resolved", func(t *testing.T) {
+		f := newFixture(t)
+		defer f.finish()
+		u := f.createUsersN(2)
+		m := f.createMR(u[0].ID)
+
+		reviews := make(map[int][]int)
+		reviews[u[1].ID] = []int{m.ID}
+		r := f.createReviews(reviews)[0]
+
+		r.IsCommented = true
+		r.UpdatedAt = 100
+		assert.NoError(t, f.UpdateReviewComment(r))
+		// threads are resolved, stall time starts over
+		r.IsCommented = false
+		r.UpdatedAt = 200
+		assert.NoError(t, f.UpdateReviewComment(r))
+		// the next refresh doesn't change anything
+		r.UpdatedAt = 300
+		assert.NoError(t, f.UpdateReviewComment(r))
+
+		rs, err := f.GetStalledReviews()
+		assert.NoError(t, err)
+		assert.Len(t, rs, 1)
+		assert.Equal(t, int64(200), rs[0].UpdatedAt)
+		assert.Equal(t, models.EscalationNone, rs[0].Step)
+	})
+	t.Run("should start over when review updated", func(t *testing.T) {
+		f := newFixture(t)
+		defer f.finish()
+		u := f.createUsersN(2)
+		m := f.createMR(u[0].ID)
+
+		reviews := make(map[int][]int)
+		reviews[u[1].ID] = []int{m.ID}
+		r := f.createReviews(reviews)[0]
+
+		assert.NoError(t, f.SaveEscalation(r, models.EscalationReminder))
+		r.UpdatedAt++
+		assert.NoError(t, f.UpdateReviewTime(r))
+
+		rs, err := f.GetStalledReviews()
+		assert.NoError(t, err)
+		assert.Len(t, rs, 1)
+		assert.Equal(t, models.EscalationNone, rs[0].Step)
+	})
+}
@@ -0,0 +1,108 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"log"
+
+	ce "tgj-bot/custom_errors"
+
+	"github.com/pkg/errors"
+)
+
+// Lock is postgres advisory lock to elect leader among replicas of the bot, it is held by session
+// of dedicated connection and released by postgres when connection of the leader is lost
+type Lock struct {
+	name string
+	key  int64
+	pool *sql.DB
+	conn *sql.Conn
+}
+
+// NewLock returns lock of the background job, replicas use the same name to compete for it
+func (c *Client) NewLock(name string) *Lock {
+	return &Lock{name: name, key: lockKey(name), pool: c.conn}
+}
+
+// lockKey returns key of advisory lock by its name
+func lockKey(name string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte("tgj-bot:" + name))
+	return int64(h.Sum64())
+}
+
+// Acquire reports whether lock is held by this replica, it is taken if it is free.
+// Lock is kept between calls until Release, so leader stays the same while it is alive
+func (l *Lock) Acquire() (bool, error) {
+	if l.pool == nil {
+		return false, errors.New("lock is not available in transaction")
+	}
+	ctx := context.Background()
+	if l.conn != nil {
+		var one int
+		if err := l.conn.QueryRowContext(ctx, `SELECT 1`).Scan(&one); err == nil {
+			return true, nil
+		}
+		// lock is gone together with session
+		_ = l.conn.Close()
+		l.conn = nil
+	}
+
+	conn, err := l.pool.Conn(ctx)
+	if err != nil {
+		return false, ce.WrapWithLog(err, fmt.Sprintf("lock %s connection", l.name))
+	}
+	var ok bool
+	if err = conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, l.key).Scan(&ok); err != nil {
+		_ = conn.Close()
+		return false, ce.WrapWithLog(err, fmt.Sprintf("acquire lock %s", l.name))
+	}
+	if !ok {
+		_ = conn.Close()
+		return false, nil
+	}
+	l.conn = conn
+	return true, nil
+}
+
+// Release gives lock to other replicas
+func (l *Lock) Release() error {
+	if l.conn == nil {
+		return nil
+	}
+	defer func() {
+		_ = l.conn.Close()
+		l.conn = nil
+	}()
+	if _, err := l.conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, l.key); err != nil {
+		return ce.WrapWithLog(err, fmt.Sprintf("release lock %s", l.name))
+	}
+	return nil
+}
+
+// WithLock runs fn while advisory lock of the name is held, so fn is serialized between replicas.
+// It waits until the lock is released by other replica
+func (c *Client) WithLock(name string, fn func() error) (err error) {
+	if c.conn == nil {
+		return errors.New("lock is not available in transaction")
+	}
+	ctx := context.Background()
+	conn, err := c.conn.Conn(ctx)
+	if err != nil {
+		return ce.WrapWithLog(err, fmt.Sprintf("lock %s connection", name))
+	}
+	defer conn.Close()
+
+	key := lockKey(name)
+	if _, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, key); err != nil {
+		return ce.WrapWithLog(err, fmt.Sprintf("acquire lock %s", name))
+	}
+	defer func() {
+		if _, unlockErr := conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, key); unlockErr != nil {
+			log.Println(ce.Wrap(unlockErr, fmt.Sprintf("release lock %s", name)))
+		}
+	}()
+	return fn()
+}
@@ -0,0 +1,58 @@
+package database
+
+import (
+	"testing"
+
+	"tgj-bot/th"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLock_Acquire(t *testing.T) {
+	f := newFixture(t)
+	defer f.finish()
+
+	name := th.String()
+	leader, replica := f.NewLock(name), f.NewLock(name)
+
+	ok, err := leader.Acquire()
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// leader keeps the lock
+	ok, err = leader.Acquire()
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = replica.Acquire()
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, leader.Release())
+	ok, err = replica.Acquire()
+	require.NoError(t, err)
+	assert.True(t, ok)
+	require.NoError(t, replica.Release())
+}
+
+func TestClient_WithLock(t *testing.T) {
+	f := newFixture(t)
+	defer f.finish()
+
+	name := th.String()
+	err := f.WithLock(name, func() error {
+		// lock is held by other session meanwhile
+		ok, err := f.NewLock(name).Acquire()
+		require.NoError(t, err)
+		assert.False(t, ok)
+		return nil
+	})
+	require.NoError(t, err)
+
+	lock := f.NewLock(name)
+	ok, err := lock.Acquire()
+	require.NoError(t, err)
+	assert.True(t, ok)
+	require.NoError(t, lock.Release())
+}
@@ -6,8 +6,21 @@ import (
 	"tgj-bot/models"
 )
 
+const mrFields = `id, url, author_id, is_closed, jira_id, jira_priority, jira_status, gitlab_id, message_id, title,
+	is_reviewed, head_sha, state, merged_at, closed_at, is_pending, pending_args, pipeline_status, stale_at, head_committed_at, is_stale_labeled`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanMR(row scanner, mr *models.MR) error {
+	return row.Scan(&mr.ID, &mr.URL, &mr.AuthorID, &mr.IsClosed, &mr.JiraID, &mr.JiraPriority, &mr.JiraStatus, &mr.GitlabID, &mr.MessageID, &mr.Title,
+		&mr.IsReviewed, &mr.HeadSHA, &mr.State, &mr.MergedAt, &mr.ClosedAt, &mr.IsPending, &mr.PendingArgs,
+		&mr.PipelineStatus, &mr.StaleAt, &mr.HeadCommittedAt, &mr.IsStaleLabeled)
+}
+
 func (c *Client) GetAllMRs() (mrs []models.MR, err error) {
-	q := `SELECT id, url, author_id, is_closed, jira_id, jira_priority, jira_status, gitlab_id FROM mrs`
+	q := `SELECT ` + mrFields + ` FROM mrs`
 	rows, err := c.db.Query(q)
 	if err != nil {
 		err = ce.WrapWithLog(err, "get opened mrs")
@@ -17,7 +30,7 @@ func (c *Client) GetAllMRs() (mrs []models.MR, err error) {
 
 	var mr models.MR
 	for rows.Next() {
-		if err = rows.Scan(&mr.ID, &mr.URL, &mr.AuthorID, &mr.IsClosed, &mr.JiraID, &mr.JiraPriority, &mr.JiraStatus, &mr.GitlabID); err != nil {
+		if err = scanMR(rows, &mr); err != nil {
 			err = ce.WrapWithLog(err, "get opened mrs")
 			return
 		}
@@ -27,9 +40,15 @@ func (c *Client) GetAllMRs() (mrs []models.MR, err error) {
 }
 
 func (c *Client) CreateMR(mr models.MR) (models.MR, error) {
-	q := `INSERT INTO mrs (url, author_id, gitlab_id, is_closed, jira_id, jira_priority, jira_status) 
-		 VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING id`
-	err := c.db.QueryRow(q, mr.URL, mr.AuthorID, mr.GitlabID, mr.IsClosed, mr.JiraID, mr.JiraPriority, mr.JiraStatus).Scan(&mr.ID)
+	if mr.State == "" {
+		mr.State = models.StateOpened
+	}
+	q := `INSERT INTO mrs (url, author_id, gitlab_id, is_closed, jira_id, jira_priority, jira_status, message_id, title,
+                  is_reviewed, head_sha, state, merged_at, closed_at, is_pending, pending_args, pipeline_status) 
+		 VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17) RETURNING id`
+	err := c.db.QueryRow(q, mr.URL, mr.AuthorID, mr.GitlabID, mr.IsClosed, mr.JiraID, mr.JiraPriority, mr.JiraStatus, mr.MessageID, mr.Title,
+		mr.IsReviewed, mr.HeadSHA, mr.State, mr.MergedAt, mr.ClosedAt, mr.IsPending, mr.PendingArgs,
+		mr.PipelineStatus).Scan(&mr.ID)
 	if err != nil {
 		err = ce.WrapWithLog(err, "create mr")
 		return mr, err
@@ -38,8 +57,10 @@ func (c *Client) CreateMR(mr models.MR) (models.MR, error) {
 }
 
 func (c *Client) SaveMR(mr models.MR) (models.MR, error) {
-	q := `UPDATE mrs SET is_closed=$2, jira_id=$3, jira_priority=$4, jira_status=$5, gitlab_id=$6 WHERE id=$1`
-	_, err := c.db.Exec(q, mr.ID, mr.IsClosed, mr.JiraID, mr.JiraPriority, mr.JiraStatus, mr.GitlabID)
+	q := `UPDATE mrs SET is_closed=$2, jira_id=$3, jira_priority=$4, jira_status=$5, gitlab_id=$6, message_id=$7, title=$8,
+                is_pending=$9 WHERE id=$1`
+	_, err := c.db.Exec(q, mr.ID, mr.IsClosed, mr.JiraID, mr.JiraPriority, mr.JiraStatus, mr.GitlabID, mr.MessageID, mr.Title,
+		mr.IsPending)
 	if err != nil {
 		err = ce.WrapWithLog(err, "save mr")
 		return mr, err
@@ -48,7 +69,7 @@ func (c *Client) SaveMR(mr models.MR) (models.MR, error) {
 }
 
 func (c *Client) GetOpenedMRs() (mrs []models.MR, err error) {
-	q := `SELECT id, url, author_id, is_closed, jira_id, jira_priority, jira_status, gitlab_id FROM mrs WHERE is_closed = FALSE`
+	q := `SELECT ` + mrFields + ` FROM mrs WHERE is_closed = FALSE`
 	rows, err := c.db.Query(q)
 	if err != nil {
 		err = ce.WrapWithLog(err, "get opened mrs")
@@ -58,7 +79,7 @@ func (c *Client) GetOpenedMRs() (mrs []models.MR, err error) {
 
 	var mr models.MR
 	for rows.Next() {
-		if err = rows.Scan(&mr.ID, &mr.URL, &mr.AuthorID, &mr.IsClosed, &mr.JiraID, &mr.JiraPriority, &mr.JiraStatus, &mr.GitlabID); err != nil {
+		if err = scanMR(rows, &mr); err != nil {
 			err = ce.WrapWithLog(err, "get opened mrs")
 			return
 		}
@@ -67,15 +88,16 @@ func (c *Client) GetOpenedMRs() (mrs []models.MR, err error) {
 	return
 }
 
-func (c *Client) CloseMRs() (mrs []models.MR, err error) {
-	q := `UPDATE mrs SET is_closed=True
+// MarkMRsReviewed marks open mrs approved by all reviewers and returns them
+func (c *Client) MarkMRsReviewed() (mrs []models.MR, err error) {
+	q := `UPDATE mrs SET is_reviewed=True
 		  WHERE  id NOT IN (SELECT DISTINCT(mr_id) 
 						    FROM reviews 
 							WHERE is_approved= FALSE)
-			AND id NOT IN (SELECT id 
-						   FROM mrs 
-			    		   WHERE is_closed=True)			
-		  RETURNING id, url, author_id, is_closed, gitlab_id, jira_status;`
+			AND is_closed = FALSE
+			AND is_reviewed = FALSE
+			AND is_pending = FALSE
+		  RETURNING ` + mrFields + `;`
 	rows, err := c.db.Query(q)
 	if err != nil {
 		err = ce.WrapWithLog(ce.ErrCloseMRs, err.Error())
@@ -85,7 +107,7 @@ func (c *Client) CloseMRs() (mrs []models.MR, err error) {
 
 	var mr models.MR
 	for rows.Next() {
-		if err = rows.Scan(&mr.ID, &mr.URL, &mr.AuthorID, &mr.IsClosed, &mr.GitlabID, &mr.JiraStatus); err != nil {
+		if err = scanMR(rows, &mr); err != nil {
 			err = ce.WrapWithLog(err, "get closed mrs id")
 			return nil, err
 		}
@@ -95,9 +117,10 @@ func (c *Client) CloseMRs() (mrs []models.MR, err error) {
 	return
 }
 
-func (c *Client) CloseMR(id int) error {
-	q := `UPDATE mrs SET is_closed=True WHERE id = $1`
-	_, err := c.db.Exec(q, id)
+// UpdateMrState saves gitlab state of mr, see models.MR.SetState
+func (c *Client) UpdateMrState(mr models.MR) error {
+	q := `UPDATE mrs SET state=$2, is_closed=$3, merged_at=$4, closed_at=$5 WHERE id = $1`
+	_, err := c.db.Exec(q, mr.ID, mr.State, mr.IsClosed, mr.MergedAt, mr.ClosedAt)
 	if err != nil {
 		err = ce.WrapWithLog(ce.ErrCloseMRs, err.Error())
 		return err
@@ -105,24 +128,125 @@ func (c *Client) CloseMR(id int) error {
 	return nil
 }
 
+// GetAbandonedMRs returns mrs closed without merge since the time, they are watched to be reopened
+func (c *Client) GetAbandonedMRs(since int64) (mrs []models.MR, err error) {
+	q := `SELECT ` + mrFields + ` FROM mrs WHERE state = $1 AND closed_at >= $2`
+	rows, err := c.db.Query(q, models.StateClosed, since)
+	if err != nil {
+		err = ce.WrapWithLog(err, "get abandoned mrs")
+		return
+	}
+	defer rows.Close()
+
+	var mr models.MR
+	for rows.Next() {
+		if err = scanMR(rows, &mr); err != nil {
+			err = ce.WrapWithLog(err, "get abandoned mrs")
+			return
+		}
+		mrs = append(mrs, mr)
+	}
+	return
+}
+
+// UpdateMrPipeline saves status of head pipeline of mr
+func (c *Client) UpdateMrPipeline(id int, status string) error {
+	q := `UPDATE mrs SET pipeline_status = $2 WHERE id = $1`
+	_, err := c.db.Exec(q, id, status)
+	if err != nil {
+		err = ce.WrapWithLog(err, "update mr pipeline")
+	}
+	return err
+}
+
+// UpdateMrStale marks mr as stale since the time, zero marks it active and forgets stale label
+func (c *Client) UpdateMrStale(id int, staleAt int64) error {
+	q := `UPDATE mrs SET stale_at = $2, is_stale_labeled = is_stale_labeled AND $2 != 0 WHERE id = $1`
+	_, err := c.db.Exec(q, id, staleAt)
+	if err != nil {
+		err = ce.WrapWithLog(err, "update mr stale")
+	}
+	return err
+}
+
+// UpdateMrMessage links mr with its status message once outbox delivered it
+func (c *Client) UpdateMrMessage(id, messageID int) error {
+	q := `UPDATE mrs SET message_id = $2 WHERE id = $1`
+	_, err := c.db.Exec(q, id, messageID)
+	if err != nil {
+		err = ce.WrapWithLog(err, "update mr message")
+	}
+	return err
+}
+
+// UpdateMrHead saves head commit of mr, commit time of previous head is forgotten
+func (c *Client) UpdateMrHead(id int, sha string) error {
+	q := `UPDATE mrs SET head_sha = $2, head_committed_at = 0 WHERE id = $1`
+	_, err := c.db.Exec(q, id, sha)
+	if err != nil {
+		err = ce.WrapWithLog(err, "update mr head")
+	}
+	return err
+}
+
+// UpdateMrHeadCommitTime caches commit time of head, it is saved only if head is still the same
+func (c *Client) UpdateMrHeadCommitTime(id int, sha string, committedAt int64) error {
+	q := `UPDATE mrs SET head_committed_at = $3 WHERE id = $1 AND head_sha = $2`
+	_, err := c.db.Exec(q, id, sha, committedAt)
+	if err != nil {
+		err = ce.WrapWithLog(err, "update mr head commit time")
+	}
+	return err
+}
+
+// MarkMrStaleLabeled remembers that stale label is added to mr
+func (c *Client) MarkMrStaleLabeled(id int) error {
+	q := `UPDATE mrs SET is_stale_labeled = TRUE WHERE id = $1`
+	_, err := c.db.Exec(q, id)
+	if err != nil {
+		err = ce.WrapWithLog(err, "mark mr stale labeled")
+	}
+	return err
+}
+
+// ResetMrReviewed returns mr to review, e.g. after approvals are reset by new commits
+func (c *Client) ResetMrReviewed(id int) error {
+	q := `UPDATE mrs SET is_reviewed = FALSE WHERE id = $1`
+	_, err := c.db.Exec(q, id)
+	if err != nil {
+		err = ce.WrapWithLog(err, "reset mr reviewed")
+	}
+	return err
+}
+
 func (c *Client) GetMrByID(id int) (mr models.MR, err error) {
-	q := `SELECT id, url, author_id, jira_id, jira_priority, jira_status, is_closed, gitlab_id FROM mrs WHERE id = $1`
-	err = c.db.QueryRow(q, id).Scan(&mr.ID, &mr.URL, &mr.AuthorID, &mr.JiraID, &mr.JiraPriority, &mr.JiraStatus, &mr.IsClosed, &mr.GitlabID)
+	q := `SELECT ` + mrFields + ` FROM mrs WHERE id = $1`
+	err = scanMR(c.db.QueryRow(q, id), &mr)
 	if err != nil {
 		err = ce.WrapWithLog(err, "get mr by id")
 	}
 	return
 }
 
+func (c *Client) GetMrByGitlabID(gitlabID int) (mr models.MR, err error) {
+	q := `SELECT ` + mrFields + ` FROM mrs WHERE gitlab_id = $1`
+	err = scanMR(c.db.QueryRow(q, gitlabID), &mr)
+	if err != nil {
+		err = ce.WrapWithLog(err, "get mr by gitlab id")
+	}
+	return
+}
+
 func (c *Client) GetMRbyURL(url string) (mr models.MR, err error) {
-	q := `SELECT id, url, author_id, is_closed, gitlab_id FROM mrs WHERE url = $1`
-	err = c.db.QueryRow(q, url).Scan(&mr.ID, &mr.URL, &mr.AuthorID, &mr.IsClosed, &mr.GitlabID)
+	q := `SELECT ` + mrFields + ` FROM mrs WHERE url = $1`
+	err = scanMR(c.db.QueryRow(q, url), &mr)
 	return
 }
 
-func (c *Client) GetUserClosedMRs(uID int, jiraStatus int) (mrs []models.MR, err error) {
-	q := `SELECT id, url, author_id, is_closed, gitlab_id, jira_id, jira_priority, jira_status
-		  FROM mrs WHERE author_id=$1 AND is_closed=True AND jira_status=$2 ORDER by jira_priority DESC`
+func (c *Client) GetUserReviewedMRs(uID int, jiraStatus int) (mrs []models.MR, err error) {
+	q := `SELECT ` + mrFields + `
+		  FROM mrs WHERE author_id=$1 AND is_reviewed=True AND jira_status=$2 AND state != 'closed'
+		  ORDER by jira_priority DESC`
 	rows, err := c.db.Query(q, uID, jiraStatus)
 	if err != nil {
 		return
@@ -131,7 +255,7 @@ func (c *Client) GetUserClosedMRs(uID int, jiraStatus int) (mrs []models.MR, err
 
 	var mr models.MR
 	for rows.Next() {
-		if err = rows.Scan(&mr.ID, &mr.URL, &mr.AuthorID, &mr.IsClosed, &mr.GitlabID, &mr.JiraID, &mr.JiraPriority, &mr.JiraStatus); err != nil {
+		if err = scanMR(rows, &mr); err != nil {
 			return
 		}
 		mrs = append(mrs, mr)
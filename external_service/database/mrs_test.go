@@ -17,12 +17,17 @@ func TestClient_SaveMR(t *testing.T) {
 		mr := models.MR{
 			URL:      th.String(),
 			AuthorID: &u.ID,
+			Title:    th.String(),
 		}
 		mr, err := f.CreateMR(mr)
 		assert.NoError(t, err)
 
 		actMR := f.getMR(mr.URL)
 		assert.Equal(t, mr.URL, actMR.URL)
+
+		actMR, err = f.GetMrByID(mr.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, mr.Title, actMR.Title)
 	})
 	t.Run("should return err if duplicate", func(t *testing.T) {
 		f := newFixture(t)
@@ -83,24 +88,43 @@ func TestClient_GetMrByID(t *testing.T) {
 	assert.Equal(t, eMr, aMr)
 }
 
-func TestClient_CloseMRs(t *testing.T) {
-	t.Run("should close mr if no reviews for it", func(t *testing.T) {
+func TestClient_GetMrByGitlabID(t *testing.T) {
+	f := newFixture(t)
+	defer f.finish()
+	u := f.createUser()
+
+	eMr, err := f.CreateMR(models.MR{URL: th.String(), AuthorID: &u.ID, GitlabID: th.Int()})
+	assert.NoError(t, err)
+
+	aMr, err := f.GetMrByGitlabID(eMr.GitlabID)
+	assert.NoError(t, err)
+	assert.Equal(t, eMr.ID, aMr.ID)
+}
+
+func TestClient_MarkMRsReviewed(t *testing.T) {
+	t.Run("should mark mr if no reviews for it", func(t *testing.T) {
 		f := newFixture(t)
 		defer f.finish()
 		u := f.createUser()
 		eMr := f.createMR(u.ID)
-		eMr.IsClosed = true
+		eMr.IsReviewed = true
 
-		mrs, err := f.CloseMRs()
+		mrs, err := f.MarkMRsReviewed()
 		assert.Len(t, mrs, 1)
 		assert.Equal(t, eMr, mrs[0])
 		assert.NoError(t, err)
 
 		aMr := f.getMR(eMr.URL)
-		assert.True(t, aMr.IsClosed)
+		assert.True(t, aMr.IsReviewed)
+		assert.False(t, aMr.IsClosed)
+
+		// already reviewed mr is not returned again
+		mrs, err = f.MarkMRsReviewed()
+		assert.NoError(t, err)
+		assert.Len(t, mrs, 0)
 	})
 
-	t.Run("should not close mr with review", func(t *testing.T) {
+	t.Run("should not mark mr with review", func(t *testing.T) {
 		f := newFixture(t)
 		defer f.finish()
 		u := f.createUsersN(2)
@@ -110,16 +134,115 @@ func TestClient_CloseMRs(t *testing.T) {
 		reviews[u[1].ID] = []int{eMr.ID}
 		f.createReviews(reviews)
 
-		mrs, err := f.CloseMRs()
+		mrs, err := f.MarkMRsReviewed()
 		assert.Len(t, mrs, 0)
 		assert.NoError(t, err)
 
 		aMr := f.getMR(eMr.URL)
-		assert.False(t, aMr.IsClosed)
+		assert.False(t, aMr.IsReviewed)
+	})
+
+	t.Run("should not mark closed mr", func(t *testing.T) {
+		f := newFixture(t)
+		defer f.finish()
+		u := f.createUser()
+		eMr := f.createMR(u.ID)
+		f.closeMR(eMr.ID)
+
+		mrs, err := f.MarkMRsReviewed()
+		assert.Len(t, mrs, 0)
+		assert.NoError(t, err)
+	})
+
+	t.Run("should not mark pending mr", func(t *testing.T) {
+		f := newFixture(t)
+		defer f.finish()
+		u := f.createUser()
+		_, err := f.CreateMR(models.MR{URL: th.String(), AuthorID: &u.ID, IsPending: true, PendingArgs: "+lead"})
+		assert.NoError(t, err)
+
+		mrs, err := f.MarkMRsReviewed()
+		assert.Len(t, mrs, 0)
+		assert.NoError(t, err)
 	})
 }
 
-func TestClient_GetUserClosedMRs(t *testing.T) {
+func TestClient_SaveMR_Pending(t *testing.T) {
+	f := newFixture(t)
+	defer f.finish()
+	u := f.createUser()
+	eMr, err := f.CreateMR(models.MR{URL: th.String(), AuthorID: &u.ID, IsPending: true, PendingArgs: "@john +lead"})
+	assert.NoError(t, err)
+
+	aMr, err := f.GetMrByID(eMr.ID)
+	assert.NoError(t, err)
+	assert.True(t, aMr.IsPending)
+	assert.Equal(t, "@john +lead", aMr.PendingArgs)
+
+	aMr.IsPending = false
+	_, err = f.SaveMR(aMr)
+	assert.NoError(t, err)
+	aMr, err = f.GetMrByID(eMr.ID)
+	assert.NoError(t, err)
+	assert.False(t, aMr.IsPending)
+}
+
+func TestClient_UpdateMrHead(t *testing.T) {
+	f := newFixture(t)
+	defer f.finish()
+	u := f.createUser()
+	eMr := f.createMR(u.ID)
+	f.closeMR(eMr.ID)
+
+	assert.NoError(t, f.UpdateMrHead(eMr.ID, "abc123"))
+	assert.NoError(t, f.ResetMrReviewed(eMr.ID))
+
+	aMr, err := f.GetMrByID(eMr.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123", aMr.HeadSHA)
+	assert.False(t, aMr.IsReviewed)
+}
+
+func TestClient_UpdateMrHeadCommitTime(t *testing.T) {
+	f := newFixture(t)
+	defer f.finish()
+	u := f.createUser()
+	eMr := f.createMR(u.ID)
+
+	assert.NoError(t, f.UpdateMrHead(eMr.ID, "abc123"))
+	assert.NoError(t, f.UpdateMrHeadCommitTime(eMr.ID, "abc123", 100))
+	// time of previous head is not saved
+	assert.NoError(t, f.UpdateMrHeadCommitTime(eMr.ID, "old", 50))
+	aMr, err := f.GetMrByID(eMr.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), aMr.HeadCommittedAt)
+
+	assert.NoError(t, f.UpdateMrHead(eMr.ID, "def456"))
+	aMr, err = f.GetMrByID(eMr.ID)
+	assert.NoError(t, err)
+	assert.Zero(t, aMr.HeadCommittedAt)
+}
+
+func TestClient_MarkMrStaleLabeled(t *testing.T) {
+	f := newFixture(t)
+	defer f.finish()
+	u := f.createUser()
+	eMr := f.createMR(u.ID)
+
+	assert.NoError(t, f.UpdateMrStale(eMr.ID, 100))
+	assert.NoError(t, f.MarkMrStaleLabeled(eMr.ID))
+	aMr, err := f.GetMrByID(eMr.ID)
+	assert.NoError(t, err)
+	assert.True(t, aMr.IsStaleLabeled)
+
+	// active mr is labeled again when it becomes stale next time
+	assert.NoError(t, f.UpdateMrStale(eMr.ID, 0))
+	aMr, err = f.GetMrByID(eMr.ID)
+	assert.NoError(t, err)
+	assert.False(t, aMr.IsStaleLabeled)
+}
+
+func TestClient_GetUserReviewedMRs(t *testing.T) {
 	f := newFixture(t)
 	defer f.finish()
 
@@ -127,11 +250,12 @@ func TestClient_GetUserClosedMRs(t *testing.T) {
 	user3 := f.createUser()
 
 	items := []models.MR{
-		{AuthorID: &user.ID, IsClosed: true, JiraStatus: 10, URL: th.String()},
-		{AuthorID: &user.ID, IsClosed: false, JiraStatus: 10, URL: th.String()},
-		{AuthorID: &user3.ID, IsClosed: true, JiraStatus: 10, URL: th.String()},
-		{AuthorID: &user.ID, IsClosed: true, JiraStatus: 20, URL: th.String()},
-		{AuthorID: &user.ID, IsClosed: true, JiraStatus: 10, URL: th.String()},
+		{AuthorID: &user.ID, IsReviewed: true, JiraStatus: 10, URL: th.String()},
+		{AuthorID: &user.ID, IsReviewed: false, JiraStatus: 10, URL: th.String()},
+		{AuthorID: &user3.ID, IsReviewed: true, JiraStatus: 10, URL: th.String()},
+		{AuthorID: &user.ID, IsReviewed: true, JiraStatus: 20, URL: th.String()},
+		{AuthorID: &user.ID, IsReviewed: true, JiraStatus: 10, URL: th.String()},
+		{AuthorID: &user.ID, IsReviewed: true, JiraStatus: 10, URL: th.String(), State: models.StateClosed, IsClosed: true},
 	}
 
 	for index, item := range items {
@@ -146,7 +270,65 @@ func TestClient_GetUserClosedMRs(t *testing.T) {
 		items[4],
 	}
 
-	values, err := f.GetUserClosedMRs(user.ID, 10)
+	values, err := f.GetUserReviewedMRs(user.ID, 10)
 	assert.NoError(t, err)
 	assert.EqualValues(t, expValues, values)
 }
+
+func TestClient_UpdateMrState(t *testing.T) {
+	f := newFixture(t)
+	defer f.finish()
+	u := f.createUser()
+	eMr := f.createMR(u.ID)
+
+	eMr.SetState(models.StateClosed, 0, 100)
+	assert.NoError(t, f.UpdateMrState(eMr))
+	aMr, err := f.GetMrByID(eMr.ID)
+	assert.NoError(t, err)
+	assert.True(t, aMr.IsClosed)
+	assert.True(t, aMr.IsAbandoned())
+	assert.Equal(t, int64(100), aMr.ClosedAt)
+
+	mrs, err := f.GetAbandonedMRs(100)
+	assert.NoError(t, err)
+	assert.Len(t, mrs, 1)
+	mrs, err = f.GetAbandonedMRs(101)
+	assert.NoError(t, err)
+	assert.Len(t, mrs, 0)
+
+	// reopened
+	eMr.SetState(models.StateOpened, 0, 100)
+	assert.NoError(t, f.UpdateMrState(eMr))
+	aMr, err = f.GetMrByID(eMr.ID)
+	assert.NoError(t, err)
+	assert.False(t, aMr.IsClosed)
+	assert.Equal(t, models.StateOpened, aMr.State)
+	assert.Equal(t, int64(0), aMr.ClosedAt)
+
+	eMr.SetState(models.StateMerged, 200, 0)
+	assert.NoError(t, f.UpdateMrState(eMr))
+	aMr, err = f.GetMrByID(eMr.ID)
+	assert.NoError(t, err)
+	assert.True(t, aMr.IsClosed)
+	assert.Equal(t, int64(200), aMr.MergedAt)
+}
+
+func TestClient_UpdateMrPipeline(t *testing.T) {
+	f := newFixture(t)
+	defer f.finish()
+	u := f.createUser()
+	eMr := f.createMR(u.ID)
+
+	assert.NoError(t, f.UpdateMrPipeline(eMr.ID, models.PipelineFailed))
+	aMr, err := f.GetMrByID(eMr.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.PipelineFailed, aMr.PipelineStatus)
+
+	rs, err := f.GetStalledReviews()
+	assert.NoError(t, err)
+	for _, r := range rs {
+		if r.MrID == eMr.ID {
+			assert.Equal(t, models.PipelineFailed, r.PipelineStatus)
+		}
+	}
+}
@@ -30,3 +30,23 @@ func (c *Client) UpdateOptionByName(name string, item interface{}) error {
 	}
 	return nil
 }
+
+// CompareAndSwapOption saves item only if option is not changed since it was loaded with prev item,
+// so only one replica succeeds
+func (c *Client) CompareAndSwapOption(name, prev string, item interface{}) (bool, error) {
+	value, err := json.Marshal(item)
+	if err != nil {
+		return false, err
+	}
+
+	q := `UPDATE options SET item=$3, updated_at=now() WHERE name=$1 AND item::text=$2`
+	res, err := c.db.Exec(q, name, prev, string(value))
+	if err != nil {
+		return false, ce.WrapWithLog(err, fmt.Sprintf("compare and swap option by name: %s", name))
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, ce.WrapWithLog(err, fmt.Sprintf("compare and swap option by name: %s", name))
+	}
+	return n == 1, nil
+}
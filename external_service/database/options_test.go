@@ -37,3 +37,20 @@ func TestClient_UpdateOptionByName(t *testing.T) {
 		assert.EqualValues(t, string(data), option.Item)
 	})
 }
+
+func TestClient_CompareAndSwapOption(t *testing.T) {
+	f := newFixture(t)
+	defer f.finish()
+
+	prev, err := f.LoadOptionByName(models.OptionLastSendNotify)
+	require.NoError(t, err)
+
+	swapped, err := f.CompareAndSwapOption(models.OptionLastSendNotify, prev.Item, models.LastSendNotifyOption{Stamp: th.Int64()})
+	require.NoError(t, err)
+	assert.True(t, swapped)
+
+	// other replica loaded the same value
+	swapped, err = f.CompareAndSwapOption(models.OptionLastSendNotify, prev.Item, models.LastSendNotifyOption{Stamp: th.Int64()})
+	require.NoError(t, err)
+	assert.False(t, swapped)
+}
@@ -0,0 +1,136 @@
+package database
+
+import (
+	"database/sql"
+	"sort"
+
+	ce "tgj-bot/custom_errors"
+	"tgj-bot/models"
+)
+
+const outboxFields = `id, chat_id, text, reply_markup, COALESCE(dedup_key, ''), status, attempts, next_attempt_at, last_error,
+					   message_id, edit_message_id, created_at, sent_at`
+
+// claims of due messages are serialized between replicas
+const outboxLock = "outbox"
+
+func scanOutboxMessage(row scanner, m *models.OutboxMessage) error {
+	return row.Scan(&m.ID, &m.ChatID, &m.Text, &m.ReplyMarkup, &m.DedupKey, &m.Status, &m.Attempts, &m.NextAttemptAt, &m.LastError,
+		&m.MessageID, &m.EditMessageID, &m.CreatedAt, &m.SentAt)
+}
+
+// EnqueueMessage puts message to the outbox, returns zero id if message with the same dedup key is already there
+func (c *Client) EnqueueMessage(m models.OutboxMessage) (id int, err error) {
+	q := `INSERT INTO outbox (chat_id, text, reply_markup, dedup_key, next_attempt_at, edit_message_id, created_at)
+		  VALUES ($1, $2, $3, $4, $5, $6, $7)
+		  ON CONFLICT (dedup_key) DO NOTHING RETURNING id`
+	dedupKey := sql.NullString{String: m.DedupKey, Valid: m.DedupKey != ""}
+	err = c.db.QueryRow(q, m.ChatID, m.Text, m.ReplyMarkup, dedupKey, m.NextAttemptAt, m.EditMessageID, m.CreatedAt).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		err = ce.WrapWithLog(err, "enqueue message")
+	}
+	return
+}
+
+// ClaimDueMessages returns pending messages which should be sent till now in order of enqueueing and hides them
+// from other claims till leaseUntil, so every message is delivered by one sender. Message is not due while earlier
+// message to the same chat is postponed or claimed, so messages of the chat are delivered in order.
+// Zero chat id claims messages of all chats
+func (c *Client) ClaimDueMessages(now, leaseUntil, chatID int64, limit int) (ms []models.OutboxMessage, err error) {
+	err = c.WithTx(func(tx *Client) error {
+		// concurrent claim sees messages leased by the previous one
+		if _, err := tx.db.Exec(`SELECT pg_advisory_xact_lock($1)`, lockKey(outboxLock)); err != nil {
+			return ce.WrapWithLog(err, "lock outbox")
+		}
+		q := `UPDATE outbox SET next_attempt_at = $4
+			  WHERE id IN (SELECT m.id
+						   FROM outbox m
+						   WHERE m.status = $1 AND m.next_attempt_at <= $2
+							 AND ($3::BIGINT = 0 OR m.chat_id = $3::BIGINT)
+							 AND NOT EXISTS (SELECT 1
+											 FROM outbox e
+											 WHERE e.chat_id = m.chat_id
+											   AND e.status = $1
+											   AND e.id < m.id
+											   AND e.next_attempt_at > $2)
+						   ORDER BY m.id
+						   LIMIT $5)
+			  RETURNING ` + outboxFields
+		rows, err := tx.db.Query(q, models.OutboxPending, now, chatID, leaseUntil, limit)
+		if err != nil {
+			return ce.WrapWithLog(err, "claim due messages")
+		}
+		defer rows.Close()
+
+		var m models.OutboxMessage
+		for rows.Next() {
+			if err = scanOutboxMessage(rows, &m); err != nil {
+				return ce.WrapWithLog(err, "claim due messages scan")
+			}
+			ms = append(ms, m)
+		}
+		return rows.Err()
+	})
+	// returning clause doesn't keep order of subquery
+	sort.Slice(ms, func(i, j int) bool { return ms[i].ID < ms[j].ID })
+	return
+}
+
+// GetMessageByDedupKey returns message enqueued with the key, zero message if there is no such one
+func (c *Client) GetMessageByDedupKey(dedupKey string) (m models.OutboxMessage, err error) {
+	q := `SELECT ` + outboxFields + ` FROM outbox WHERE dedup_key = $1`
+	err = scanOutboxMessage(c.db.QueryRow(q, dedupKey), &m)
+	if err == sql.ErrNoRows {
+		return models.OutboxMessage{}, nil
+	}
+	if err != nil {
+		err = ce.WrapWithLog(err, "get message by dedup key")
+	}
+	return
+}
+
+// GetLastMessageVersion returns the latest text of sent message with its edits, even not delivered yet,
+// zero message if there is no such one
+func (c *Client) GetLastMessageVersion(chatID int64, messageID int) (m models.OutboxMessage, err error) {
+	q := `SELECT ` + outboxFields + ` FROM outbox
+		  WHERE chat_id = $1 AND (edit_message_id = $2 OR message_id = $2 AND edit_message_id = 0)
+		  ORDER BY id DESC
+		  LIMIT 1`
+	err = scanOutboxMessage(c.db.QueryRow(q, chatID, messageID), &m)
+	if err == sql.ErrNoRows {
+		return models.OutboxMessage{}, nil
+	}
+	if err != nil {
+		err = ce.WrapWithLog(err, "get last message version")
+	}
+	return
+}
+
+func (c *Client) MarkMessageSent(id, messageID int, sentAt int64) (err error) {
+	q := `UPDATE outbox SET status = $2, message_id = $3, sent_at = $4, attempts = attempts + 1 WHERE id = $1`
+	if _, err = c.db.Exec(q, id, models.OutboxSent, messageID, sentAt); err != nil {
+		err = ce.WrapWithLog(err, "mark message sent")
+	}
+	return
+}
+
+// MarkMessageRetry records failed attempt and postpones the next one
+func (c *Client) MarkMessageRetry(id int, nextAttemptAt int64, lastError string) (err error) {
+	q := `UPDATE outbox SET attempts = attempts + 1, next_attempt_at = $2, last_error = $3 WHERE id = $1`
+	if _, err = c.db.Exec(q, id, nextAttemptAt, lastError); err != nil {
+		err = ce.WrapWithLog(err, "mark message retry")
+	}
+	return
+}
+
+// MarkMessageFailed gives up delivery of the message
+func (c *Client) MarkMessageFailed(id int, lastError string) (err error) {
+	q := `UPDATE outbox SET status = $2, attempts = attempts + 1, last_error = $3 WHERE id = $1`
+	if _, err = c.db.Exec(q, id, models.OutboxFailed, lastError); err != nil {
+		err = ce.WrapWithLog(err, "mark message failed")
+	}
+	return
+}
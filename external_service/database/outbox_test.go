@@ -0,0 +1,147 @@
+package database
+
+import (
+	"testing"
+
+	"tgj-bot/models"
+	"tgj-bot/th"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func (f *fixture) enqueueMessage(dedupKey string, nextAttemptAt int64) models.OutboxMessage {
+	m := models.OutboxMessage{
+		ChatID:        int64(th.Int()),
+		Text:          th.String(),
+		DedupKey:      dedupKey,
+		NextAttemptAt: nextAttemptAt,
+		CreatedAt:     nextAttemptAt,
+	}
+	id, err := f.EnqueueMessage(m)
+	assert.NoError(f.T, err)
+	m.ID = id
+	m.Status = models.OutboxPending
+	return m
+}
+
+func TestClient_EnqueueMessage(t *testing.T) {
+	t.Run("should skip duplicate", func(t *testing.T) {
+		f := newFixture(t)
+		defer f.finish()
+
+		m := f.enqueueMessage("daily:2020-01-01", 1)
+		assert.NotZero(t, m.ID)
+		dup := f.enqueueMessage("daily:2020-01-01", 1)
+		assert.Zero(t, dup.ID)
+
+		ms, err := f.ClaimDueMessages(1, 1, 0, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.OutboxMessage{m}, ms)
+	})
+	t.Run("should not deduplicate without key", func(t *testing.T) {
+		f := newFixture(t)
+		defer f.finish()
+
+		assert.NotZero(t, f.enqueueMessage("", 1).ID)
+		assert.NotZero(t, f.enqueueMessage("", 1).ID)
+
+		ms, err := f.ClaimDueMessages(1, 1, 0, 10)
+		assert.NoError(t, err)
+		assert.Len(t, ms, 2)
+	})
+}
+
+func TestClient_ClaimDueMessages(t *testing.T) {
+	f := newFixture(t)
+	defer f.finish()
+
+	due := f.enqueueMessage("", 10)
+	f.enqueueMessage("", 20)
+	sent := f.enqueueMessage("", 10)
+	failed := f.enqueueMessage("", 10)
+	retry := f.enqueueMessage("", 10)
+
+	assert.NoError(t, f.MarkMessageSent(sent.ID, 1, 10))
+	assert.NoError(t, f.MarkMessageFailed(failed.ID, "Forbidden"))
+	assert.NoError(t, f.MarkMessageRetry(retry.ID, 30, "Too Many Requests"))
+
+	ms, err := f.ClaimDueMessages(15, 15, 0, 10)
+	assert.NoError(t, err)
+	due.NextAttemptAt = 15
+	assert.Equal(t, []models.OutboxMessage{due}, ms)
+
+	ms, err = f.ClaimDueMessages(30, 40, 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, ms, 3)
+	assert.Equal(t, retry.ID, ms[2].ID)
+	assert.Equal(t, 1, ms[2].Attempts)
+	assert.Equal(t, "Too Many Requests", ms[2].LastError)
+	assert.Equal(t, int64(40), ms[2].NextAttemptAt)
+
+	// claimed messages are hidden till the end of lease
+	ms, err = f.ClaimDueMessages(35, 45, 0, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, ms)
+
+	ms, err = f.ClaimDueMessages(40, 50, 0, 1)
+	assert.NoError(t, err)
+	assert.Len(t, ms, 1)
+	assert.Equal(t, due.ID, ms[0].ID)
+}
+
+func TestClient_ClaimDueMessages_Order(t *testing.T) {
+	f := newFixture(t)
+	defer f.finish()
+
+	first := f.enqueueMessage("", 10)
+	second := models.OutboxMessage{ChatID: first.ChatID, Text: th.String(), NextAttemptAt: 10, CreatedAt: 10}
+	id, err := f.EnqueueMessage(second)
+	assert.NoError(t, err)
+	other := f.enqueueMessage("", 10)
+
+	// the second message waits for postponed first one
+	assert.NoError(t, f.MarkMessageRetry(first.ID, 30, "Too Many Requests"))
+	ms, err := f.ClaimDueMessages(20, 20, first.ChatID, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, ms)
+
+	ms, err = f.ClaimDueMessages(30, 40, first.ChatID, 10)
+	assert.NoError(t, err)
+	assert.Len(t, ms, 2)
+	assert.Equal(t, first.ID, ms[0].ID)
+	assert.Equal(t, id, ms[1].ID)
+
+	ms, err = f.ClaimDueMessages(30, 30, 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, ms, 1)
+	assert.Equal(t, other.ID, ms[0].ID)
+}
+
+func TestClient_GetLastMessageVersion(t *testing.T) {
+	f := newFixture(t)
+	defer f.finish()
+
+	m := f.enqueueMessage("status:1", 10)
+	assert.NoError(t, f.MarkMessageSent(m.ID, 7, 10))
+
+	last, err := f.GetLastMessageVersion(m.ChatID, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, m.ID, last.ID)
+
+	found, err := f.GetMessageByDedupKey("status:1")
+	assert.NoError(t, err)
+	assert.Equal(t, 7, found.MessageID)
+
+	edit := models.OutboxMessage{ChatID: m.ChatID, Text: th.String(), EditMessageID: 7, NextAttemptAt: 20, CreatedAt: 20}
+	edit.ID, err = f.EnqueueMessage(edit)
+	assert.NoError(t, err)
+
+	last, err = f.GetLastMessageVersion(m.ChatID, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, edit.ID, last.ID)
+	assert.Equal(t, edit.Text, last.Text)
+
+	last, err = f.GetLastMessageVersion(m.ChatID, 8)
+	assert.NoError(t, err)
+	assert.Zero(t, last.ID)
+}
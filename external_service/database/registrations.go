@@ -0,0 +1,38 @@
+package database
+
+import (
+	ce "tgj-bot/custom_errors"
+	"tgj-bot/models"
+)
+
+// SavePendingRegistration replaces previous registration request of the telegram user
+func (c *Client) SavePendingRegistration(r models.PendingRegistration) (err error) {
+	q := `INSERT INTO pending_registrations (telegram_id, telegram_username, gitlab_id, gitlab_name, role, code, expires_at, created_at)
+		  VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		  ON CONFLICT (telegram_id)
+		  DO UPDATE SET telegram_username = $2, gitlab_id = $3, gitlab_name = $4, role = $5, code = $6, expires_at = $7, created_at = $8`
+	_, err = c.db.Exec(q, r.TelegramID, r.TelegramUsername, r.GitlabID, r.GitlabName, r.Role, r.Code, r.ExpiresAt, r.CreatedAt)
+	if err != nil {
+		err = ce.WrapWithLog(err, "save pending registration")
+	}
+	return
+}
+
+func (c *Client) GetPendingRegistration(tgID string) (r models.PendingRegistration, err error) {
+	q := `SELECT telegram_id, telegram_username, gitlab_id, gitlab_name, role, code, expires_at, created_at
+		  FROM pending_registrations
+		  WHERE telegram_id = $1`
+	err = c.db.QueryRow(q, tgID).Scan(&r.TelegramID, &r.TelegramUsername, &r.GitlabID, &r.GitlabName, &r.Role, &r.Code, &r.ExpiresAt, &r.CreatedAt)
+	if err != nil {
+		err = ce.WrapWithLog(err, "get pending registration")
+	}
+	return
+}
+
+func (c *Client) DeletePendingRegistration(tgID string) (err error) {
+	q := `DELETE FROM pending_registrations WHERE telegram_id = $1`
+	if _, err = c.db.Exec(q, tgID); err != nil {
+		err = ce.WrapWithLog(err, "delete pending registration")
+	}
+	return
+}
@@ -0,0 +1,40 @@
+package database
+
+import (
+	"testing"
+
+	"tgj-bot/models"
+	"tgj-bot/th"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_PendingRegistration(t *testing.T) {
+	f := newFixture(t)
+	defer f.finish()
+
+	exp := models.PendingRegistration{
+		TelegramID:       th.String(),
+		TelegramUsername: th.String(),
+		GitlabID:         th.Int(),
+		GitlabName:       th.String(),
+		Role:             models.Developer,
+		Code:             th.String(),
+		ExpiresAt:        2,
+		CreatedAt:        1,
+	}
+	assert.NoError(t, f.SavePendingRegistration(exp))
+
+	// repeated request replaces the code
+	exp.Code = th.String()
+	exp.Role = models.Lead
+	assert.NoError(t, f.SavePendingRegistration(exp))
+
+	act, err := f.GetPendingRegistration(exp.TelegramID)
+	assert.NoError(t, err)
+	assert.Equal(t, exp, act)
+
+	assert.NoError(t, f.DeletePendingRegistration(exp.TelegramID))
+	_, err = f.GetPendingRegistration(exp.TelegramID)
+	assert.Error(t, err)
+}
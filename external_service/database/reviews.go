@@ -43,10 +43,12 @@ func (c *Client) UpdateReviewApprove(r models.Review) error {
 	return nil
 }
 
+// UpdateReviewComment saves whether reviewer has unresolved threads, update time is changed only
+// together with the flag, so stall time of escalations is not reset by every refresh of mr
 func (c *Client) UpdateReviewComment(r models.Review) (err error) {
 	q := `UPDATE reviews 
 			SET is_commented = $1,
-				updated_at = $2
+				updated_at = CASE WHEN is_commented != $1 THEN $2 ELSE updated_at END
 		  WHERE user_id = $3
   			AND mr_id = $4`
 	_, err = c.db.Exec(q, r.IsCommented, r.UpdatedAt, r.UserID, r.MrID)
@@ -57,6 +59,93 @@ func (c *Client) UpdateReviewComment(r models.Review) (err error) {
 	return
 }
 
+func (c *Client) UpdateReviewTaken(r models.Review) (err error) {
+	q := `UPDATE reviews 
+			SET is_taken = $1,
+				updated_at = $2
+		  WHERE user_id = $3
+  			AND mr_id = $4`
+	_, err = c.db.Exec(q, r.IsTaken, r.UpdatedAt, r.UserID, r.MrID)
+	if err != nil {
+		err = ce.WrapWithLog(err, "update review taken")
+	}
+
+	return
+}
+
+func (c *Client) UpdateReviewTime(r models.Review) (err error) {
+	q := `UPDATE reviews SET updated_at = $1 WHERE user_id = $2 AND mr_id = $3`
+	_, err = c.db.Exec(q, r.UpdatedAt, r.UserID, r.MrID)
+	if err != nil {
+		err = ce.WrapWithLog(err, "update review time")
+	}
+
+	return
+}
+
+// ResetReviewsTime restarts waiting time of not approved reviews of mr
+func (c *Client) ResetReviewsTime(mrID int, updatedAt int64) (err error) {
+	q := `UPDATE reviews SET updated_at = $2 WHERE mr_id = $1 AND is_approved = FALSE`
+	_, err = c.db.Exec(q, mrID, updatedAt)
+	if err != nil {
+		err = ce.WrapWithLog(err, "reset reviews time")
+	}
+	return
+}
+
+func (c *Client) GetReview(mrID, uID int) (r models.Review, err error) {
+	q := `SELECT mr_id, user_id, is_approved, is_commented, is_taken, updated_at, reset_at 
+		  FROM reviews 
+		  WHERE mr_id = $1 
+		    AND user_id = $2`
+	err = c.db.QueryRow(q, mrID, uID).Scan(&r.MrID, &r.UserID, &r.IsApproved, &r.IsCommented, &r.IsTaken, &r.UpdatedAt, &r.ResetAt)
+	if err != nil {
+		err = ce.WrapWithLog(err, "get review")
+	}
+	return
+}
+
+// ResetReviewApprove returns approved review to reviewer after new commits
+func (c *Client) ResetReviewApprove(r models.Review) (err error) {
+	q := `UPDATE reviews 
+			SET is_approved = FALSE,
+				updated_at = $1,
+				reset_at = $2
+		  WHERE user_id = $3 
+  			AND mr_id = $4`
+	_, err = c.db.Exec(q, r.UpdatedAt, r.ResetAt, r.UserID, r.MrID)
+	if err != nil {
+		err = ce.WrapWithLog(err, "reset review approve")
+	}
+	return
+}
+
+func (c *Client) GetReviewersByMrID(mrID int) (rs []models.Reviewer, err error) {
+	q := `SELECT u.id, u.telegram_id, u.telegram_username, u.role, u.gitlab_id, u.gitlab_name,
+				 r.is_approved, r.is_commented, r.is_taken, r.reset_at
+		  FROM reviews r
+		  JOIN users u on r.user_id = u.id
+		  WHERE r.mr_id = $1
+		  ORDER BY u.id`
+	rows, err := c.db.Query(q, mrID)
+	if err != nil {
+		err = ce.WrapWithLog(err, "get reviewers by mr id")
+		return
+	}
+	defer rows.Close()
+
+	var r models.Reviewer
+	for rows.Next() {
+		if err = rows.Scan(&r.ID, &r.TelegramID, &r.TelegramUsername, &r.Role, &r.GitlabID, &r.GitlabName,
+			&r.IsApproved, &r.IsCommented, &r.IsTaken, &r.ResetAt); err != nil {
+			err = ce.WrapWithLog(err, "get reviewers by mr id scan")
+			return
+		}
+		rs = append(rs, r)
+	}
+	return
+}
+
 func (c *Client) GetReviewMRsByUserID(uID int) (ids []int, err error) {
 	q := `SELECT mr_id FROM reviews WHERE is_approved = FALSE AND user_id = $1`
 	rows, err := c.db.Query(q, uID)
@@ -84,6 +173,7 @@ func (c *Client) GetOpenedReviewsByUserID(uID int) (rs []models.Review, err erro
 		    AND is_approved = FALSE
 		    AND is_commented = FALSE
 		    AND m.is_closed = FALSE
+		    AND m.stale_at = 0
 		  ORDER BY m.jira_priority DESC`
 	rows, err := c.db.Query(q, uID)
 	if err != nil {
@@ -100,3 +190,36 @@ func (c *Client) GetOpenedReviewsByUserID(uID int) (rs []models.Review, err erro
 	}
 	return
 }
+
+func (c *Client) SaveReviewChange(rc models.ReviewChange) (err error) {
+	q := `INSERT INTO review_history (mr_id, from_user_id, to_user_id, reason, changed_by, created_at)
+		  VALUES ($1, $2, $3, $4, $5, $6)`
+	_, err = c.db.Exec(q, rc.MrID, rc.FromUserID, rc.ToUserID, rc.Reason, rc.ChangedBy, rc.CreatedAt)
+	if err != nil {
+		err = ce.WrapWithLog(err, "save review change")
+	}
+	return
+}
+
+func (c *Client) GetReviewChanges(mrID int) (cs []models.ReviewChange, err error) {
+	q := `SELECT id, mr_id, from_user_id, to_user_id, reason, changed_by, created_at
+		  FROM review_history
+		  WHERE mr_id = $1
+		  ORDER BY id`
+	rows, err := c.db.Query(q, mrID)
+	if err != nil {
+		err = ce.WrapWithLog(err, "get review changes")
+		return
+	}
+	defer rows.Close()
+
+	var rc models.ReviewChange
+	for rows.Next() {
+		if err = rows.Scan(&rc.ID, &rc.MrID, &rc.FromUserID, &rc.ToUserID, &rc.Reason, &rc.ChangedBy, &rc.CreatedAt); err != nil {
+			err = ce.WrapWithLog(err, "get review changes scan")
+			return
+		}
+		cs = append(cs, rc)
+	}
+	return
+}
@@ -64,6 +64,115 @@ func TestClient_UpdateReviewComment(t *testing.T) {
 	assert.Equal(t, r, actR)
 }
 
+func TestClient_UpdateReviewTaken(t *testing.T) {
+	f := newFixture(t)
+	defer f.finish()
+	u := f.createUser()
+	eMr := f.createMR(u.ID)
+
+	reviews := make(map[int][]int)
+	reviews[u.ID] = []int{eMr.ID}
+	r := f.createReviews(reviews)[0]
+
+	r.IsTaken = true
+	r.UpdatedAt = th.Int64()
+
+	assert.NoError(t, f.UpdateReviewTaken(r))
+
+	actR := f.getReviewsByUser(u.ID)[0]
+	assert.Equal(t, r, actR)
+}
+
+func TestClient_UpdateReviewTime(t *testing.T) {
+	f := newFixture(t)
+	defer f.finish()
+	u := f.createUser()
+	eMr := f.createMR(u.ID)
+
+	reviews := make(map[int][]int)
+	reviews[u.ID] = []int{eMr.ID}
+	r := f.createReviews(reviews)[0]
+
+	r.UpdatedAt = th.Int64()
+
+	assert.NoError(t, f.UpdateReviewTime(r))
+
+	actR := f.getReviewsByUser(u.ID)[0]
+	assert.Equal(t, r, actR)
+}
+
+func TestClient_ResetReviewsTime(t *testing.T) {
+	f := newFixture(t)
+	defer f.finish()
+	u := f.createUsersN(2)
+	eMr := f.createMR(u[0].ID)
+
+	reviews := make(map[int][]int)
+	reviews[u[0].ID] = []int{eMr.ID}
+	reviews[u[1].ID] = []int{eMr.ID}
+	f.createReviews(reviews)
+	approved := models.Review{MrID: eMr.ID, UserID: u[1].ID, IsApproved: true, UpdatedAt: 1}
+	assert.NoError(t, f.UpdateReviewApprove(approved))
+
+	updatedAt := th.Int64()
+	assert.NoError(t, f.ResetReviewsTime(eMr.ID, updatedAt))
+
+	assert.Equal(t, updatedAt, f.getReviewsByUser(u[0].ID)[0].UpdatedAt)
+	assert.Equal(t, int64(1), f.getReviewsByUser(u[1].ID)[0].UpdatedAt)
+}
+
+func TestClient_GetReview(t *testing.T) {
+	t.Run("should get review", func(t *testing.T) {
+		f := newFixture(t)
+		defer f.finish()
+		u := f.createUser()
+		eMr := f.createMR(u.ID)
+
+		reviews := make(map[int][]int)
+		reviews[u.ID] = []int{eMr.ID}
+		r := f.createReviews(reviews)[0]
+
+		actR, err := f.GetReview(eMr.ID, u.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, r, actR)
+	})
+	t.Run("should return err if user is not reviewer", func(t *testing.T) {
+		f := newFixture(t)
+		defer f.finish()
+		u := f.createUsersN(2)
+		eMr := f.createMR(u[0].ID)
+
+		reviews := make(map[int][]int)
+		reviews[u[0].ID] = []int{eMr.ID}
+		f.createReviews(reviews)
+
+		_, err := f.GetReview(eMr.ID, u[1].ID)
+		assert.Error(t, err)
+	})
+}
+
+func TestClient_ResetReviewApprove(t *testing.T) {
+	f := newFixture(t)
+	defer f.finish()
+	u := f.createUsersN(2)
+	eMr := f.createMR(u[0].ID)
+
+	reviews := make(map[int][]int)
+	reviews[u[1].ID] = []int{eMr.ID}
+	r := f.createReviews(reviews)[0]
+	r.IsApproved = true
+	assert.NoError(t, f.UpdateReviewApprove(r))
+
+	r.IsApproved = false
+	r.UpdatedAt = int64(th.Int())
+	r.ResetAt = int64(th.Int())
+	assert.NoError(t, f.ResetReviewApprove(r))
+
+	actR, err := f.GetReview(eMr.ID, u[1].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, r, actR)
+}
+
 func TestClient_GetUserReviewMRs(t *testing.T) {
 	f := newFixture(t)
 	defer f.finish()
@@ -84,3 +193,50 @@ func TestClient_GetUserReviewMRs(t *testing.T) {
 		assert.True(t, isContain(m0Arr, id))
 	}
 }
+
+func TestClient_GetReviewersByMrID(t *testing.T) {
+	f := newFixture(t)
+	defer f.finish()
+	u := f.createUsersN(3)
+	m := f.createMR(u[0].ID)
+
+	reviews := make(map[int][]int)
+	reviews[u[1].ID] = []int{m.ID}
+	reviews[u[2].ID] = []int{m.ID}
+	rs := f.createReviews(reviews)
+
+	approved := rs[0]
+	approved.IsApproved = true
+	assert.NoError(t, f.UpdateReviewApprove(approved))
+
+	reviewers, err := f.GetReviewersByMrID(m.ID)
+	assert.NoError(t, err)
+	assert.Len(t, reviewers, len(reviews))
+	for _, r := range reviewers {
+		assert.True(t, isContain([]int{u[1].ID, u[2].ID}, r.ID))
+		assert.Equal(t, r.ID == approved.UserID, r.IsApproved)
+	}
+}
+
+func TestClient_SaveReviewChange(t *testing.T) {
+	f := newFixture(t)
+	defer f.finish()
+	u := f.createUsersN(3)
+	m := f.createMR(u[0].ID)
+
+	exp := []models.ReviewChange{
+		{MrID: m.ID, FromUserID: u[1].ID, ToUserID: u[2].ID, Reason: "vacation", ChangedBy: u[1].TelegramID, CreatedAt: int64(th.Int())},
+		{MrID: m.ID, FromUserID: u[2].ID, ToUserID: u[1].ID, CreatedAt: int64(th.Int())},
+	}
+	for _, rc := range exp {
+		assert.NoError(t, f.SaveReviewChange(rc))
+	}
+
+	act, err := f.GetReviewChanges(m.ID)
+	assert.NoError(t, err)
+	assert.Len(t, act, len(exp))
+	for i := range act {
+		exp[i].ID = act[i].ID
+		assert.Equal(t, exp[i], act[i])
+	}
+}
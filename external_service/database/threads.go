@@ -0,0 +1,56 @@
+package database
+
+import (
+	ce "tgj-bot/custom_errors"
+	"tgj-bot/models"
+
+	"github.com/lib/pq"
+)
+
+// SaveThreads replaces threads of mr with actual ones
+func (c *Client) SaveThreads(mrID int, ts []models.Thread) (err error) {
+	q := `INSERT INTO threads (mr_id, discussion_id, note_id, opened_by, last_note_by, is_resolvable, is_resolved, awaiting_id)
+		  VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		  ON CONFLICT (mr_id, discussion_id)
+		  DO UPDATE SET last_note_by = $5, is_resolvable = $6, is_resolved = $7, awaiting_id = $8`
+	ids := make([]string, 0, len(ts))
+	for _, t := range ts {
+		if _, err = c.db.Exec(q, mrID, t.DiscussionID, t.NoteID, t.OpenedBy, t.LastNoteBy, t.IsResolvable, t.IsResolved, t.AwaitingID); err != nil {
+			return ce.WrapWithLog(err, "save thread")
+		}
+		ids = append(ids, t.DiscussionID)
+	}
+
+	// deleted in gitlab
+	q = `DELETE FROM threads WHERE mr_id = $1 AND NOT (discussion_id = ANY($2))`
+	if _, err = c.db.Exec(q, mrID, pq.Array(ids)); err != nil {
+		err = ce.WrapWithLog(err, "delete threads")
+	}
+	return
+}
+
+// GetAwaitingThreads returns threads of open mrs waiting for reply of the gitlab user
+func (c *Client) GetAwaitingThreads(gitlabID int) (ts []models.Thread, err error) {
+	q := `SELECT t.mr_id, t.discussion_id, t.note_id, t.opened_by, t.last_note_by, t.is_resolvable, t.is_resolved, t.awaiting_id
+		  FROM threads t
+		  JOIN mrs m ON t.mr_id = m.id
+		  WHERE t.awaiting_id = $1
+		    AND m.is_closed = FALSE
+		  ORDER BY t.mr_id, t.note_id`
+	rows, err := c.db.Query(q, gitlabID)
+	if err != nil {
+		err = ce.WrapWithLog(err, "get awaiting threads")
+		return
+	}
+	defer rows.Close()
+
+	var t models.Thread
+	for rows.Next() {
+		if err = rows.Scan(&t.MrID, &t.DiscussionID, &t.NoteID, &t.OpenedBy, &t.LastNoteBy, &t.IsResolvable, &t.IsResolved, &t.AwaitingID); err != nil {
+			err = ce.WrapWithLog(err, "get awaiting threads scan")
+			return
+		}
+		ts = append(ts, t)
+	}
+	return
+}
@@ -0,0 +1,45 @@
+package database
+
+import (
+	"testing"
+
+	"tgj-bot/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_SaveThreads(t *testing.T) {
+	f := newFixture(t)
+	defer f.finish()
+	u := f.createUser()
+	m := f.createMRs(u.ID, 2)
+
+	ts := []models.Thread{
+		{MrID: m[0].ID, DiscussionID: "a", NoteID: 1, OpenedBy: 10, LastNoteBy: 10, IsResolvable: true, AwaitingID: 20},
+		{MrID: m[0].ID, DiscussionID: "b", NoteID: 2, OpenedBy: 11, LastNoteBy: 20, IsResolvable: true, AwaitingID: 11},
+		{MrID: m[0].ID, DiscussionID: "c", NoteID: 3, OpenedBy: 10, LastNoteBy: 10, IsResolvable: true, IsResolved: true},
+	}
+	assert.NoError(t, f.SaveThreads(m[0].ID, ts))
+	assert.NoError(t, f.SaveThreads(m[1].ID, []models.Thread{
+		{MrID: m[1].ID, DiscussionID: "a", NoteID: 4, OpenedBy: 10, LastNoteBy: 10, IsResolvable: true, AwaitingID: 20},
+	}))
+	f.closeMR(m[1].ID)
+
+	act, err := f.GetAwaitingThreads(20)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Thread{ts[0]}, act)
+
+	// thread is answered, another one is deleted in gitlab
+	ts[0].LastNoteBy, ts[0].AwaitingID = 20, 10
+	assert.NoError(t, f.SaveThreads(m[0].ID, ts[:1]))
+
+	act, err = f.GetAwaitingThreads(20)
+	assert.NoError(t, err)
+	assert.Empty(t, act)
+	act, err = f.GetAwaitingThreads(10)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Thread{ts[0]}, act)
+	act, err = f.GetAwaitingThreads(11)
+	assert.NoError(t, err)
+	assert.Empty(t, act)
+}
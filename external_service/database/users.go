@@ -5,18 +5,30 @@ import (
 	"fmt"
 	"log"
 	"strconv"
+	"time"
 
 	ce "tgj-bot/custom_errors"
 	"tgj-bot/models"
+
+	"github.com/lib/pq"
 )
 
+const userFields = `id, telegram_id, telegram_username, gitlab_id, jira_id, is_active, role, gitlab_name, is_admin`
+
+func scanUser(row scanner, u *models.User) error {
+	return row.Scan(&u.ID, &u.TelegramID, &u.TelegramUsername, &u.GitlabID, &u.JiraID, &u.IsActive, &u.Role, &u.GitlabName, &u.IsAdmin)
+}
+
+// SaveUser registers user or updates registered one. Empty role keeps role of registered user,
+// new user becomes developer
 func (c *Client) SaveUser(u models.User) (int, error) {
 	q := `INSERT INTO  users (telegram_id, telegram_username, gitlab_id, jira_id, is_active, role, gitlab_name)
-		  VALUES ($1, $2, $3, $4, $5, $6, $7)
-		  ON CONFLICT ON CONSTRAINT users_telegram_username_key
-		  DO UPDATE SET telegram_id = $1, role = $6, gitlab_id = $3, gitlab_name = $7
+		  VALUES ($1, $2, $3, $4, $5, COALESCE(NULLIF($6, ''), $8), $7)
+		  ON CONFLICT (telegram_id)
+		  DO UPDATE SET telegram_username = $2, role = COALESCE(NULLIF($6, ''), users.role), gitlab_id = $3, gitlab_name = $7,
+		                is_active = CASE WHEN users.deleted_at > 0 THEN $5 ELSE users.is_active END, deleted_at = 0
 		  RETURNING id`
-	err := c.db.QueryRow(q, u.TelegramID, u.TelegramUsername, u.GitlabID, u.JiraID, u.IsActive, u.Role, u.GitlabName).Scan(&u.ID)
+	err := c.db.QueryRow(q, u.TelegramID, u.TelegramUsername, u.GitlabID, u.JiraID, u.IsActive, u.Role, u.GitlabName, models.Developer).Scan(&u.ID)
 	if err != nil {
 		err = ce.WrapWithLog(err, ce.ErrCreateUser.Error())
 		return 0, err
@@ -24,17 +36,31 @@ func (c *Client) SaveUser(u models.User) (int, error) {
 	return u.ID, nil
 }
 
-func (c *Client) ChangeIsActiveUser(telegramUsername string, isActive bool) (err error) {
-	q := `UPDATE users SET is_active = $1 WHERE telegram_username = $2`
-	_, err = c.db.Exec(q, isActive, telegramUsername)
+func (c *Client) ChangeIsActiveUser(uID int, isActive bool) (err error) {
+	q := `UPDATE users SET is_active = $1 WHERE id = $2`
+	_, err = c.db.Exec(q, isActive, uID)
 	if err != nil {
 		err = ce.WrapWithLog(err, ce.ErrChangeUserActivity.Error())
 	}
 	return
 }
 
+// UpdateTelegramUsername saves actual username of telegram account. Username could be taken
+// from another account, so it is cleared at users who had it before
+func (c *Client) UpdateTelegramUsername(tgID, tgUsername string) (err error) {
+	q := `UPDATE users
+		  SET telegram_username = CASE WHEN telegram_id = $1 THEN $2 ELSE '' END
+		  WHERE (telegram_id = $1 AND telegram_username != $2)
+		     OR (telegram_id != $1 AND telegram_username = $2 AND $2 != '')`
+	_, err = c.db.Exec(q, tgID, tgUsername)
+	if err != nil {
+		err = ce.WrapWithLog(err, "update telegram username")
+	}
+	return
+}
+
 func (c *Client) GetUsersWithPayload(exceptTelegramID string) (ups models.UsersPayload, err error) {
-	q := `WITH mr_ids AS (SELECT id FROM mrs WHERE is_closed = FALSE)
+	q := `WITH mr_ids AS (SELECT id FROM mrs WHERE is_closed = FALSE AND stale_at = 0)
 		  SELECT id, 
        			 telegram_id,
        			 telegram_username,
@@ -70,10 +96,12 @@ func (c *Client) GetUsersWithPayload(exceptTelegramID string) (ups models.UsersP
 }
 
 func (c *Client) GetUserByTgUsername(tgUname string) (u models.User, err error) {
-	q := `SELECT id, telegram_id, telegram_username, gitlab_id, jira_id, is_active, role, gitlab_name 
+	q := `SELECT ` + userFields + `
 		  FROM users 
-          WHERE telegram_username = $1`
-	err = c.db.QueryRow(q, tgUname).Scan(&u.ID, &u.TelegramID, &u.TelegramUsername, &u.GitlabID, &u.JiraID, &u.IsActive, &u.Role, &u.GitlabName)
+          WHERE telegram_username = $1
+            AND telegram_username != ''
+            AND deleted_at = 0`
+	err = scanUser(c.db.QueryRow(q, tgUname), &u)
 	if err != nil {
 		err = ce.WrapWithLog(err, "get user by telegram username")
 		return
@@ -81,6 +109,19 @@ func (c *Client) GetUserByTgUsername(tgUname string) (u models.User, err error)
 	return
 }
 
+func (c *Client) GetUserByTgID(tgID string) (u models.User, err error) {
+	q := `SELECT ` + userFields + `
+		  FROM users 
+          WHERE telegram_id = $1
+            AND deleted_at = 0`
+	err = scanUser(c.db.QueryRow(q, tgID), &u)
+	if err != nil {
+		err = ce.WrapWithLog(err, "get user by telegram id")
+		return
+	}
+	return
+}
+
 func (c *Client) GetUserByGitlabID(id interface{}) (u models.User, err error) {
 	switch id.(type) {
 	case int:
@@ -92,10 +133,10 @@ func (c *Client) GetUserByGitlabID(id interface{}) (u models.User, err error) {
 		return
 	}
 
-	q := `SELECT id, telegram_id, telegram_username, gitlab_id, jira_id, is_active, role, gitlab_name 
+	q := `SELECT ` + userFields + `
 		  FROM users 
           WHERE gitlab_id = $1`
-	err = c.db.QueryRow(q, id).Scan(&u.ID, &u.TelegramID, &u.TelegramUsername, &u.GitlabID, &u.JiraID, &u.IsActive, &u.Role, &u.GitlabName)
+	err = scanUser(c.db.QueryRow(q, id), &u)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			log.Printf("get users by gitlab id: %v:", err)
@@ -133,29 +174,33 @@ func (c *Client) GetUsersByMrID(id int) (us []models.UserBrief, err error) {
 }
 
 func (c *Client) GetUserForReallocateMR(u models.UserBrief, mID int) (up models.UserPayload, err error) {
-
-	q := `SELECT id, 
-       			 telegram_id,
-       			 telegram_username,
-       			 role, 
-                 (SELECT count(*) 
-                  FROM reviews r 
-                  WHERE r.user_id = id
-                    AND r.is_approved = FALSE) AS payload,
-       			 gitlab_id, 
-       			 gitlab_name
-          FROM users
-          WHERE is_active = TRUE 
-            AND role = $1
-            AND id != $2
-            AND id NOT IN (SELECT user_id 
-            			   FROM reviews 
-            			   WHERE mr_id = $3 
-            			     AND user_id != $2)
+	// payload counts open reviews of mrs on review like GetUsersWithPayload, author can't review own mr
+	q := `SELECT u.id,
+       			 u.telegram_id,
+       			 u.telegram_username,
+       			 u.role,
+                 (SELECT count(*)
+                  FROM reviews r
+                  JOIN mrs m ON m.id = r.mr_id
+                  WHERE r.user_id = u.id
+                    AND r.is_approved = FALSE
+                    AND m.is_closed = FALSE
+                    AND m.stale_at = 0) AS payload,
+       			 u.gitlab_id,
+       			 u.gitlab_name
+          FROM users u
+          WHERE u.is_active = TRUE
+            AND u.role = $1
+            AND u.id != $2
+            AND u.id != (SELECT author_id FROM mrs WHERE id = $3)
+            AND u.id NOT IN (SELECT user_id
+            			     FROM reviews
+            			     WHERE mr_id = $3
+            			       AND user_id != $2)
 		  ORDER BY payload
 		  LIMIT 1;`
 
-	err = c.db.QueryRow(q, u.Role, u.ID, mID).Scan(&up.ID, &up.TelegramID, &up.TelegramUsername, &up.Role, &up.Payload, &u.GitlabID, &u.GitlabName)
+	err = c.db.QueryRow(q, u.Role, u.ID, mID).Scan(&up.ID, &up.TelegramID, &up.TelegramUsername, &up.Role, &up.Payload, &up.GitlabID, &up.GitlabName)
 	if err != nil {
 		err = ce.WrapWithLog(err, "get user for reallocate mr")
 		return
@@ -164,7 +209,7 @@ func (c *Client) GetUserForReallocateMR(u models.UserBrief, mID int) (up models.
 }
 
 func (c *Client) GetActiveUsers() (us models.UserList, err error) {
-	q := `SELECT id, telegram_id, telegram_username, gitlab_id, jira_id, is_active, role, gitlab_name FROM users WHERE is_active = TRUE`
+	q := `SELECT ` + userFields + ` FROM users WHERE is_active = TRUE`
 
 	rows, err := c.db.Query(q)
 	if err != nil {
@@ -174,7 +219,7 @@ func (c *Client) GetActiveUsers() (us models.UserList, err error) {
 
 	var u models.User
 	for rows.Next() {
-		if err = rows.Scan(&u.ID, &u.TelegramID, &u.TelegramUsername, &u.GitlabID, &u.JiraID, &u.IsActive, &u.Role, &u.GitlabName); err != nil {
+		if err = scanUser(rows, &u); err != nil {
 			return
 		}
 		us = append(us, u)
@@ -183,12 +228,87 @@ func (c *Client) GetActiveUsers() (us models.UserList, err error) {
 }
 
 func (c *Client) GetUserByID(ID int) (u models.User, err error) {
-	q := `SELECT id, telegram_id, telegram_username, gitlab_id, jira_id, is_active, role, gitlab_name 
+	q := `SELECT ` + userFields + `
 		  FROM users WHERE id = $1`
-	err = c.db.QueryRow(q, ID).Scan(&u.ID, &u.TelegramID, &u.TelegramUsername, &u.GitlabID, &u.JiraID, &u.IsActive, &u.Role, &u.GitlabName)
+	err = scanUser(c.db.QueryRow(q, ID), &u)
 	if err != nil {
 		err = ce.WrapWithLog(err, "get user by telegram username")
 		return
 	}
 	return
 }
+
+func (c *Client) GetUserSettings(uID int) (s models.UserSettings, err error) {
+	q := `SELECT id, notify_mode, quiet_from, quiet_to, has_private_chat FROM users WHERE id = $1`
+	err = c.db.QueryRow(q, uID).Scan(&s.UserID, &s.NotifyMode, &s.QuietFrom, &s.QuietTo, &s.HasPrivateChat)
+	if err != nil {
+		err = ce.WrapWithLog(err, "get user settings")
+	}
+	return
+}
+
+func (c *Client) SaveUserSettings(s models.UserSettings) (err error) {
+	q := `UPDATE users SET notify_mode = $2, quiet_from = $3, quiet_to = $4 WHERE id = $1`
+	_, err = c.db.Exec(q, s.UserID, s.NotifyMode, s.QuietFrom, s.QuietTo)
+	if err != nil {
+		err = ce.WrapWithLog(err, "save user settings")
+	}
+	return
+}
+
+func (c *Client) SetPrivateChat(tgID string, hasPrivateChat bool) (err error) {
+	q := `UPDATE users SET has_private_chat = $1 WHERE telegram_id = $2`
+	_, err = c.db.Exec(q, hasPrivateChat, tgID)
+	if err != nil {
+		err = ce.WrapWithLog(err, "set private chat")
+	}
+	return
+}
+
+// GrantAdmins makes admins of registered users with the telegram ids
+func (c *Client) GrantAdmins(tgIDs []string) (err error) {
+	q := `UPDATE users SET is_admin = TRUE WHERE telegram_id = ANY($1)`
+	_, err = c.db.Exec(q, pq.Array(tgIDs))
+	if err != nil {
+		err = ce.WrapWithLog(err, "grant admins")
+	}
+	return
+}
+
+// GetUsers returns registered users in alphabetical order
+func (c *Client) GetUsers() (us models.UserList, err error) {
+	q := `SELECT ` + userFields + ` FROM users WHERE deleted_at = 0 ORDER BY telegram_username`
+	rows, err := c.db.Query(q)
+	if err != nil {
+		err = ce.WrapWithLog(err, "get users")
+		return
+	}
+	defer rows.Close()
+
+	var u models.User
+	for rows.Next() {
+		if err = scanUser(rows, &u); err != nil {
+			err = ce.WrapWithLog(err, "get users scan")
+			return
+		}
+		us = append(us, u)
+	}
+	return
+}
+
+func (c *Client) ChangeUserRole(uID int, role models.Role) (err error) {
+	q := `UPDATE users SET role = $2 WHERE id = $1`
+	if _, err = c.db.Exec(q, uID, role); err != nil {
+		err = ce.WrapWithLog(err, "change user role")
+	}
+	return
+}
+
+// DeleteUser unregisters user, the row is kept for history of reviews
+func (c *Client) DeleteUser(uID int) (err error) {
+	q := `UPDATE users SET is_active = FALSE, deleted_at = $2 WHERE id = $1`
+	if _, err = c.db.Exec(q, uID, time.Now().Unix()); err != nil {
+		err = ce.WrapWithLog(err, "delete user")
+	}
+	return
+}
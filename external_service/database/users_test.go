@@ -41,7 +41,7 @@ func TestClient_ChangeIsActiveUser(t *testing.T) {
 		expUsers := f.createUsersN(4)
 
 		for _, u := range expUsers {
-			assert.NoError(t, f.ChangeIsActiveUser(u.TelegramUsername, !u.IsActive))
+			assert.NoError(t, f.ChangeIsActiveUser(f.getUser(u.TelegramUsername).ID, !u.IsActive))
 			actUser := f.getUser(u.TelegramUsername)
 			u.ID = actUser.ID
 			assert.Equal(t, u.IsActive, !actUser.IsActive)
@@ -53,7 +53,7 @@ func TestClient_ChangeIsActiveUser(t *testing.T) {
 		expUsers := f.createUsersN(4)
 
 		for _, u := range expUsers {
-			assert.NoError(t, f.ChangeIsActiveUser(u.TelegramUsername, u.IsActive))
+			assert.NoError(t, f.ChangeIsActiveUser(f.getUser(u.TelegramUsername).ID, u.IsActive))
 			actUser := f.getUser(u.TelegramUsername)
 			u.ID = actUser.ID
 			assert.Equal(t, u, actUser)
@@ -72,6 +72,42 @@ func TestClient_GetUserByTgUsername(t *testing.T) {
 	assert.Equal(t, expU, actU)
 }
 
+func TestClient_UpdateTelegramUsername(t *testing.T) {
+	f := newFixture(t)
+	defer f.finish()
+	u := f.createUsersN(2)
+
+	// username is changed
+	assert.NoError(t, f.UpdateTelegramUsername(u[0].TelegramID, "new_name"))
+	actU, err := f.GetUserByTgID(u[0].TelegramID)
+	assert.NoError(t, err)
+	assert.Equal(t, "new_name", actU.TelegramUsername)
+
+	// username is taken by another account
+	assert.NoError(t, f.UpdateTelegramUsername(u[1].TelegramID, "new_name"))
+	actU, err = f.GetUserByTgID(u[0].TelegramID)
+	assert.NoError(t, err)
+	assert.Empty(t, actU.TelegramUsername)
+	actU, err = f.GetUserByTgUsername("new_name")
+	assert.NoError(t, err)
+	assert.Equal(t, u[1].ID, actU.ID)
+
+	// username is removed, user without username is not found by empty one
+	assert.NoError(t, f.UpdateTelegramUsername(u[1].TelegramID, ""))
+	_, err = f.GetUserByTgUsername("")
+	assert.Error(t, err)
+}
+
+func TestClient_GetUserByTgID(t *testing.T) {
+	f := newFixture(t)
+	defer f.finish()
+
+	expU := f.createUser()
+	actU, err := f.GetUserByTgID(expU.TelegramID)
+	assert.NoError(t, err)
+	assert.Equal(t, expU, actU)
+}
+
 func TestClient_GetUserByGitlabID(t *testing.T) {
 	t.Run("should get by string gitlab id", func(t *testing.T) {
 		f := newFixture(t)
@@ -131,6 +167,22 @@ func TestClient_GetUsersWithPayload(t *testing.T) {
 	assert.Equal(t, len(m0Arr), ups[0].Payload)
 }
 
+func TestClient_GetUsersWithPayload_Stale(t *testing.T) {
+	f := newFixture(t)
+	defer f.finish()
+	u := f.createUsersN(2)
+	m0 := f.createMRs(u[0].ID, 2)
+
+	reviews := make(map[int][]int)
+	reviews[u[1].ID] = []int{m0[0].ID, m0[1].ID}
+	f.createReviews(reviews)
+	assert.NoError(t, f.UpdateMrStale(m0[0].ID, th.Int64()))
+
+	ups, err := f.GetUsersWithPayload(u[0].TelegramID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, ups[0].Payload)
+}
+
 func TestClient_GetUserForReallocateMR(t *testing.T) {
 	f := newFixture(t)
 	defer f.finish()
@@ -148,3 +200,154 @@ func TestClient_GetUserForReallocateMR(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, u[2].ID, up.ID)
 }
+
+func TestClient_GetUserForReallocateMR_AuthorAndClosed(t *testing.T) {
+	f := newFixture(t)
+	defer f.finish()
+	u := f.createUsersN(4)
+	mr := f.createMR(u[1].ID)
+	open := f.createMRs(u[0].ID, 2)
+	closed := f.createMRs(u[0].ID, 2)
+
+	f.createReviews(map[int][]int{
+		u[0].ID: {mr.ID},
+		// reviews of closed and stale mrs are not counted in payload
+		u[2].ID: {open[0].ID, closed[0].ID, closed[1].ID},
+		u[3].ID: {open[0].ID, open[1].ID},
+	})
+	closed[0].IsClosed = true
+	assert.NoError(t, f.UpdateMrState(closed[0]))
+	assert.NoError(t, f.UpdateMrStale(closed[1].ID, 1))
+
+	// author has the least payload, but can't review own mr
+	up, err := f.GetUserForReallocateMR(u[0].UserBrief, mr.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, u[2].ID, up.ID)
+	assert.Equal(t, 1, up.Payload)
+}
+
+func TestClient_SaveUserSettings(t *testing.T) {
+	t.Run("should return defaults", func(t *testing.T) {
+		f := newFixture(t)
+		defer f.finish()
+		u := f.createUser()
+
+		s, err := f.GetUserSettings(u.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, models.UserSettings{UserID: u.ID, NotifyMode: models.NotifyGroup}, s)
+	})
+	t.Run("should save settings", func(t *testing.T) {
+		f := newFixture(t)
+		defer f.finish()
+		u := f.createUser()
+
+		exp := models.UserSettings{
+			UserID:     u.ID,
+			NotifyMode: models.NotifyDM,
+			QuietFrom:  22,
+			QuietTo:    8,
+		}
+		assert.NoError(t, f.SaveUserSettings(exp))
+		assert.NoError(t, f.SetPrivateChat(u.TelegramID, true))
+		exp.HasPrivateChat = true
+
+		s, err := f.GetUserSettings(u.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, exp, s)
+	})
+}
+
+func TestClient_GrantAdmins(t *testing.T) {
+	f := newFixture(t)
+	defer f.finish()
+	u := f.createUsersN(3)
+
+	assert.NoError(t, f.GrantAdmins([]string{u[0].TelegramID, u[2].TelegramID, th.String()}))
+
+	for i, exp := range []bool{true, false, true} {
+		actUser, err := f.GetUserByID(u[i].ID)
+		assert.NoError(t, err)
+		assert.Equal(t, exp, actUser.IsAdmin)
+	}
+
+	// saving user does not revoke admin
+	assert.NoError(t, f.ChangeIsActiveUser(u[0].ID, false))
+	_, err := f.SaveUser(u[0])
+	assert.NoError(t, err)
+	actUser, err := f.GetUserByTgUsername(u[0].TelegramUsername)
+	assert.NoError(t, err)
+	assert.True(t, actUser.IsAdmin)
+}
+
+func TestClient_SaveUser_ReRegister(t *testing.T) {
+	f := newFixture(t)
+	defer f.finish()
+	u := f.createUser()
+
+	u.TelegramUsername = th.String()
+	u.GitlabID = th.Int()
+	u.GitlabName = th.String()
+	u.Role = models.Lead
+	id, err := f.SaveUser(u)
+	assert.NoError(t, err)
+	assert.Equal(t, u.ID, id)
+
+	actU, err := f.GetUserByTgID(u.TelegramID)
+	assert.NoError(t, err)
+	assert.Equal(t, u, actU)
+
+	// role is kept if it is not given
+	u.Role = ""
+	_, err = f.SaveUser(u)
+	assert.NoError(t, err)
+	actU, err = f.GetUserByTgID(u.TelegramID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.Lead, actU.Role)
+
+	newU := models.User{UserBrief: models.UserBrief{TelegramID: th.String(), TelegramUsername: th.String()}, IsActive: true}
+	newU.ID, err = f.SaveUser(newU)
+	assert.NoError(t, err)
+	actU, err = f.GetUserByTgID(newU.TelegramID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.Developer, actU.Role)
+}
+
+func TestClient_DeleteUser(t *testing.T) {
+	f := newFixture(t)
+	defer f.finish()
+	u := f.createUsersN(3)
+
+	assert.NoError(t, f.DeleteUser(u[1].ID))
+
+	us, err := f.GetUsers()
+	assert.NoError(t, err)
+	assert.Len(t, us, 2)
+	for _, actU := range us {
+		assert.NotEqual(t, u[1].ID, actU.ID)
+	}
+
+	_, err = f.GetUserByTgUsername(u[1].TelegramUsername)
+	assert.Error(t, err)
+	// history is kept
+	actU, err := f.GetUserByID(u[1].ID)
+	assert.NoError(t, err)
+	assert.False(t, actU.IsActive)
+
+	// registration restores the user
+	_, err = f.SaveUser(u[1])
+	assert.NoError(t, err)
+	actU, err = f.GetUserByTgUsername(u[1].TelegramUsername)
+	assert.NoError(t, err)
+	assert.True(t, actU.IsActive)
+}
+
+func TestClient_ChangeUserRole(t *testing.T) {
+	f := newFixture(t)
+	defer f.finish()
+	u := f.createUser()
+
+	assert.NoError(t, f.ChangeUserRole(u.ID, models.Lead))
+	actU, err := f.GetUserByID(u.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.Lead, actU.Role)
+}
@@ -4,10 +4,11 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"log"
+	"net/http"
+	"strings"
+	"time"
 
-	"github.com/davecgh/go-spew/spew"
 	"github.com/xanzy/go-gitlab"
 
 	ce "tgj-bot/custom_errors"
@@ -23,11 +24,15 @@ const (
 	endComment   = "//"
 )
 
+// merge status of mr with conflicts
+const cannotBeMerged = "cannot_be_merged"
+
 type GitlabService interface {
-	CheckMrLikes(mrID int) (users map[int]struct{}, err error)
-	CheckMrComments(mrID int) (users map[int]struct{}, err error)
+	CheckMrLikes(mrID int) (users map[int]time.Time, err error)
+	MrThreads(mrID int) ([]models.Thread, error)
 	GetMrAuthorID(mrID int) (int, error)
-	MrIsOpen(mrID int) (bool, error)
+	GetMrByID(mrID int) (*GitlabMR, error)
+	MergeWhenPipelineSucceeds(mrID int, sha string) error
 	GetMrTitle(mrID int) (string, error)
 }
 
@@ -35,6 +40,10 @@ type GitlabConfig struct {
 	Token     string `json:"token"`
 	ProjectID string `json:"project_id"`
 	MRBaseURL string `json:"mr_base_url"`
+	// limit of requests to gitlab api, zero means no limit
+	RequestsPerSecond float64 `json:"requests_per_second"`
+	// number of mrs refreshed from gitlab at once
+	RefreshWorkers int `json:"refresh_workers"`
 }
 
 type Client struct {
@@ -46,10 +55,28 @@ type GitlabMR struct {
 	ID       int
 	Title    string
 	AuthorID int
+	State    string
+	// head commit of source branch
+	SHA string
+	// unix time, zero if not merged or closed
+	MergedAt int64
+	ClosedAt int64
+	// marked as Draft or WIP, not ready for review
+	IsDraft bool
+	// status of head pipeline, empty if there is no pipeline
+	PipelineStatus string
+	// can_be_merged, cannot_be_merged or unchecked
+	MergeStatus string
+}
+
+// HasConflicts reports whether gitlab found conflicts with target branch
+func (mr GitlabMR) HasConflicts() bool {
+	return mr.MergeStatus == cannotBeMerged
 }
 
 func RunGitlab(cfg GitlabConfig) (client Client, err error) {
-	client.Gitlab = gitlab.NewClient(nil, cfg.Token)
+	httpClient := &http.Client{Transport: newRateLimiter(cfg.RequestsPerSecond, http.DefaultTransport)}
+	client.Gitlab = gitlab.NewClient(httpClient, cfg.Token)
 
 	if err = client.Gitlab.SetBaseURL("https://git.itv.restr.im/"); err != nil {
 		return
@@ -70,48 +97,105 @@ func RunGitlab(cfg GitlabConfig) (client Client, err error) {
 // добавить в таблицу МР колонку автор_ид
 //
 
-// return list of users with emoji on mr
-func (c *Client) CheckMrLikes(mrID int) (users map[int]struct{}, err error) {
-	emojies, resp, err := c.Gitlab.AwardEmoji.ListMergeRequestAwardEmoji(c.Project.ID, mrID, nil)
-	respBody, _ := ioutil.ReadAll(resp.Body)
-	log.Println("Emojies resp:", string(respBody))
+// return users with emoji on mr and time of their latest award
+func (c *Client) CheckMrLikes(mrID int) (users map[int]time.Time, err error) {
+	var emojies []*gitlab.AwardEmoji
+	err = paginate(func(opt gitlab.ListOptions) (*gitlab.Response, error) {
+		o := gitlab.ListAwardEmojiOptions(opt)
+		page, resp, err := c.Gitlab.AwardEmoji.ListMergeRequestAwardEmoji(c.Project.ID, mrID, &o)
+		emojies = append(emojies, page...)
+		return resp, err
+	})
 	if err != nil {
 		return
 	}
-	log.Println("Emojies:", emojies)
+	log.Printf("Mr %d emojies: %d", mrID, len(emojies))
 
-	users = make(map[int]struct{})
+	users = make(map[int]time.Time)
 	for _, e := range emojies {
-		if _, found := users[e.User.ID]; !found {
-			users[e.User.ID] = struct{}{}
+		var awardedAt time.Time
+		if e.CreatedAt != nil {
+			awardedAt = *e.CreatedAt
+		}
+		if awardedAt.After(users[e.User.ID]) || users[e.User.ID].IsZero() {
+			users[e.User.ID] = awardedAt
 		}
 	}
 	return
 }
 
-// если есть открытые комметны то нотификацию получает хост МРа
-// return list of users with open comment flag
-func (c *Client) CheckMrComments(mrID int) (users map[int]bool, err error) {
-	discussions, resp, err := c.Gitlab.Discussions.ListMergeRequestDiscussions(c.Project.ID, mrID, nil)
-	respBody, _ := ioutil.ReadAll(resp.Body)
-	log.Println("Comments resp:", string(respBody))
+// MrThreads returns discussions of mr with their participants, system notes are skipped
+func (c *Client) MrThreads(mrID int) ([]models.Thread, error) {
+	discussions, err := c.listDiscussions(mrID)
 	if err != nil {
-		log.Println(err)
 		return nil, err
 	}
-	log.Println("Comments:", discussions)
 
-	users = make(map[int]bool)
-	for _, discussion := range discussions {
-		lastComment := discussion.Notes[len(discussion.Notes)-1]
-		// if discussion resolved, we need to reset is_commented flag
-		users[discussion.Notes[0].Author.ID] = users[discussion.Notes[0].Author.ID] || !lastComment.Resolved
+	var threads []models.Thread
+	for _, d := range discussions {
+		t := models.Thread{DiscussionID: d.ID}
+		for _, n := range d.Notes {
+			if n.System {
+				continue
+			}
+			if t.NoteID == 0 {
+				t.NoteID = n.ID
+				t.OpenedBy = n.Author.ID
+				t.IsResolvable = n.Resolvable
+			}
+			if !containsID(t.Participants, n.Author.ID) {
+				t.Participants = append(t.Participants, n.Author.ID)
+			}
+			t.LastNoteBy = n.Author.ID
+			t.LastNoteAt = unixTime(n.CreatedAt)
+			t.IsResolved = n.Resolved
+		}
+		if t.NoteID != 0 {
+			threads = append(threads, t)
+		}
 	}
+	return threads, nil
+}
+
+// listDiscussions loads all discussions of mr page by page
+func (c *Client) listDiscussions(mrID int) (discussions []*gitlab.Discussion, err error) {
+	err = paginate(func(opt gitlab.ListOptions) (*gitlab.Response, error) {
+		o := gitlab.ListMergeRequestDiscussionsOptions(opt)
+		page, resp, err := c.Gitlab.Discussions.ListMergeRequestDiscussions(c.Project.ID, mrID, &o)
+		discussions = append(discussions, page...)
+		return resp, err
+	})
 	return
 }
 
+// isDraftTitle checks title prefixes of draft mrs, newer gitlab versions mark drafts by title only
+func isDraftTitle(title string) bool {
+	title = strings.ToLower(strings.TrimSpace(title))
+	for _, prefix := range []string{"draft:", "[draft]", "(draft)", "wip:", "[wip]"} {
+		if strings.HasPrefix(title, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func unixTime(t *time.Time) int64 {
+	if t == nil {
+		return 0
+	}
+	return t.Unix()
+}
+
+func containsID(ids []int, id int) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *Client) GetMrByID(mrID int) (*GitlabMR, error) {
-	spew.Dump(c)
 	item, _, err := c.Gitlab.MergeRequests.GetMergeRequest(c.Project.ID, mrID, nil)
 	if err != nil {
 		return nil, err
@@ -121,20 +205,19 @@ func (c *Client) GetMrByID(mrID int) (*GitlabMR, error) {
 		ID:       item.ID,
 		Title:    item.Title,
 		AuthorID: item.Author.ID,
+		State:    item.State,
+		SHA:      item.SHA,
+		MergedAt: unixTime(item.MergedAt),
+		ClosedAt: unixTime(item.ClosedAt),
+		IsDraft:  item.WorkInProgress || isDraftTitle(item.Title),
+
+		PipelineStatus: item.Pipeline.Status,
+		MergeStatus:    item.MergeStatus,
 	}
 
 	return mr, nil
 }
 
-func (c *Client) MrIsOpen(mrID int) (bool, error) {
-	mr, _, err := c.Gitlab.MergeRequests.GetMergeRequest(c.Project.ID, mrID, nil)
-	if err != nil {
-		return false, err
-	}
-	log.Printf("Get MR from gitlab %d: %v\n", mrID, mr)
-	return mr.State == opened, nil
-}
-
 func (c *Client) GetUserByID(gitlabID int) (name string, err error) {
 	user, _, err := c.Gitlab.Users.GetUser(gitlabID)
 	log.Println("Get user by gitlab id:", user)
@@ -145,12 +228,18 @@ func (c *Client) GetUserByID(gitlabID int) (name string, err error) {
 }
 
 func (c *Client) GetUserByName(gitlabName string) (id int, err error) {
-	options := &gitlab.ListUsersOptions{Username: &gitlabName}
-	userList, _, err := c.Gitlab.Users.ListUsers(options)
-	log.Println("Get user by gitlab name:", userList)
+	var userList []*gitlab.User
+	err = paginate(func(opt gitlab.ListOptions) (*gitlab.Response, error) {
+		page, resp, err := c.Gitlab.Users.ListUsers(&gitlab.ListUsersOptions{ListOptions: opt, Username: &gitlabName})
+		userList = append(userList, page...)
+		return resp, err
+	})
 	if err != nil {
 		return
 	}
+	if len(userList) == 0 {
+		return 0, errors.New(fmt.Sprintf("user not found by name=%s", gitlabName))
+	}
 	if len(userList) > 1 {
 		return 0, errors.New(fmt.Sprintf("more than one matches found by name=%s\ntry to use gitlab id instead", gitlabName))
 	}
@@ -159,7 +248,6 @@ func (c *Client) GetUserByName(gitlabName string) (id int, err error) {
 
 func (c *Client) WriteReviewers(mrID int, reviewers []models.UserBrief) error {
 	description, err := c.getMrDescription(mrID)
-	spew.Dump(description)
 	if err != nil {
 		ce.WrapWithLog(err, "get mr description fail")
 		return err
@@ -172,9 +260,24 @@ func (c *Client) WriteReviewers(mrID int, reviewers []models.UserBrief) error {
 	description += endComment
 	opt := &gitlab.UpdateMergeRequestOptions{Description: &description}
 	_, _, err = c.Gitlab.MergeRequests.UpdateMergeRequest(c.Project.ID, mrID, opt)
-	spew.Dump(opt, c.Project.ID, mrID)
-	spew.Dump(description, err)
+	if err != nil {
+		log.Printf("Write reviewers to mr %d: %v", mrID, err)
+	}
+	return err
+}
 
+// ClearReviewers removes reviewers written by WriteReviewers from description of mr
+func (c *Client) ClearReviewers(mrID int) error {
+	description, err := c.getMrDescription(mrID)
+	if err != nil {
+		return err
+	}
+	cleared := strings.TrimRight(removeReviewersFromDescription(description), "\n")
+	if cleared == description {
+		return nil
+	}
+	opt := &gitlab.UpdateMergeRequestOptions{Description: &cleared}
+	_, _, err = c.Gitlab.MergeRequests.UpdateMergeRequest(c.Project.ID, mrID, opt)
 	return err
 }
 
@@ -228,8 +331,134 @@ func removeReviewersFromDescription(description string) string {
 
 func (c *Client) SetLabelToMR(mrID int, labels ...string) error {
 	opt := &gitlab.UpdateMergeRequestOptions{Labels: labels}
-	_, resp, err := c.Gitlab.MergeRequests.UpdateMergeRequest(c.Project.ID, mrID, opt)
-	respBody, _ := ioutil.ReadAll(resp.Body)
-	log.Println("Set Label to MR:", string(respBody))
+	_, _, err := c.Gitlab.MergeRequests.UpdateMergeRequest(c.Project.ID, mrID, opt)
 	return err
 }
+
+// HasIssueNote reports whether the user left comment containing the text on the project issue
+func (c *Client) HasIssueNote(issueIID, authorID int, text string) (bool, error) {
+	orderBy, sort := "created_at", "desc"
+	var notes []*gitlab.Note
+	err := paginate(func(opt gitlab.ListOptions) (*gitlab.Response, error) {
+		o := &gitlab.ListIssueNotesOptions{ListOptions: opt, OrderBy: &orderBy, Sort: &sort}
+		page, resp, err := c.Gitlab.Notes.ListIssueNotes(c.Project.ID, issueIID, o)
+		notes = append(notes, page...)
+		return resp, err
+	})
+	if err != nil {
+		return false, err
+	}
+	for _, n := range notes {
+		if !n.System && n.Author.ID == authorID && strings.Contains(n.Body, text) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// HasUserStatus reports whether gitlab status message of the user contains the text
+func (c *Client) HasUserStatus(userID int, text string) (bool, error) {
+	status, _, err := c.Gitlab.Users.GetUserStatus(userID)
+	if err != nil {
+		return false, err
+	}
+	return strings.Contains(status.Message, text), nil
+}
+
+// RemoveLabelFromMR keeps other labels of mr
+func (c *Client) RemoveLabelFromMR(mrID int, label string) error {
+	mr, err := c.loadMR(mrID)
+	if err != nil {
+		return err
+	}
+	labels := gitlab.Labels{}
+	for _, l := range mr.Labels {
+		if l != label {
+			labels = append(labels, l)
+		}
+	}
+	if len(labels) == len(mr.Labels) {
+		return nil
+	}
+	if len(labels) == 0 {
+		// empty value clears labels, empty list is omitted
+		labels = gitlab.Labels{""}
+	}
+	_, _, err = c.Gitlab.MergeRequests.UpdateMergeRequest(c.Project.ID, mrID, &gitlab.UpdateMergeRequestOptions{Labels: labels})
+	return err
+}
+
+// AddLabelToMR keeps other labels of mr
+func (c *Client) AddLabelToMR(mrID int, label string) error {
+	mr, err := c.loadMR(mrID)
+	if err != nil {
+		return err
+	}
+	for _, l := range mr.Labels {
+		if l == label {
+			return nil
+		}
+	}
+	labels := append(gitlab.Labels{}, mr.Labels...)
+	_, _, err = c.Gitlab.MergeRequests.UpdateMergeRequest(c.Project.ID, mrID, &gitlab.UpdateMergeRequestOptions{Labels: append(labels, label)})
+	return err
+}
+
+// CloseMR closes mr in gitlab without merge
+func (c *Client) CloseMR(mrID int) error {
+	opt := &gitlab.UpdateMergeRequestOptions{StateEvent: gitlab.String("close")}
+	_, _, err := c.Gitlab.MergeRequests.UpdateMergeRequest(c.Project.ID, mrID, opt)
+	return err
+}
+
+// CommitTime returns unix time of the commit
+func (c *Client) CommitTime(sha string) (int64, error) {
+	commit, _, err := c.Gitlab.Commits.GetCommit(c.Project.ID, sha)
+	if err != nil {
+		return 0, err
+	}
+	return unixTime(commit.CommittedDate), nil
+}
+
+// MergeWhenPipelineSucceeds sets mr to be merged by gitlab, sha guards against merging commits pushed after review
+func (c *Client) MergeWhenPipelineSucceeds(mrID int, sha string) error {
+	opt := &gitlab.AcceptMergeRequestOptions{MergeWhenPipelineSucceeds: gitlab.Bool(true)}
+	if sha != "" {
+		opt.SHA = &sha
+	}
+	_, _, err := c.Gitlab.MergeRequests.AcceptMergeRequest(c.Project.ID, mrID, opt)
+	return err
+}
+
+// ChangedFiles returns paths of files changed between commits
+func (c *Client) ChangedFiles(fromSHA, toSHA string) ([]string, error) {
+	compare, _, err := c.Gitlab.Repositories.Compare(c.Project.ID, &gitlab.CompareOptions{From: &fromSHA, To: &toSHA})
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, d := range compare.Diffs {
+		files = append(files, d.NewPath)
+		if d.OldPath != d.NewPath {
+			files = append(files, d.OldPath)
+		}
+	}
+	return files, nil
+}
+
+// ThreadFiles returns files commented in diff threads of mr by thread author gitlab id
+func (c *Client) ThreadFiles(mrID int) (map[int][]string, error) {
+	discussions, err := c.listDiscussions(mrID)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[int][]string)
+	for _, d := range discussions {
+		if len(d.Notes) == 0 || d.Notes[0].Position == nil {
+			continue
+		}
+		n := d.Notes[0]
+		files[n.Author.ID] = append(files[n.Author.ID], n.Position.NewPath, n.Position.OldPath)
+	}
+	return files, nil
+}
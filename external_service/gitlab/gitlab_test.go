@@ -1,8 +1,14 @@
 package gitlab_
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xanzy/go-gitlab"
 )
 
 func Test_string(t *testing.T) {
@@ -10,3 +16,65 @@ func Test_string(t *testing.T) {
 	fmt.Println("1. ", removeReviewersFromDescription(description))
 	fmt.Println("2. ", removeReviewersFromDescription(" // Reviewers:  "))
 }
+
+func TestPaginate(t *testing.T) {
+	next := map[int]int{1: 2, 2: 3, 3: 0}
+	var pages []int
+	err := paginate(func(opt gitlab.ListOptions) (*gitlab.Response, error) {
+		assert.Equal(t, perPage, opt.PerPage)
+		pages = append(pages, opt.Page)
+		return &gitlab.Response{NextPage: next[opt.Page]}, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, pages)
+
+	pages = nil
+	err = paginate(func(opt gitlab.ListOptions) (*gitlab.Response, error) {
+		pages = append(pages, opt.Page)
+		if opt.Page == 2 {
+			return nil, errors.New("unauthorized")
+		}
+		return &gitlab.Response{NextPage: next[opt.Page]}, nil
+	})
+	assert.EqualError(t, err, "unauthorized")
+	assert.Equal(t, []int{1, 2}, pages)
+}
+
+func TestIsDraftTitle(t *testing.T) {
+	testCases := []struct {
+		title   string
+		isDraft bool
+	}{
+		{"[NC-42] Fix login", false},
+		{"Draft: [NC-42] Fix login", true},
+		{"draft:[NC-42] Fix login", true},
+		{"[Draft] [NC-42] Fix login", true},
+		{"(Draft) [NC-42] Fix login", true},
+		{"WIP: [NC-42] Fix login", true},
+		{"[WIP] [NC-42] Fix login", true},
+		{"[NC-42] Draft: fix login", false},
+	}
+
+	for i, tc := range testCases {
+		if isDraftTitle(tc.title) != tc.isDraft {
+			t.Fatalf("failed at index %d", i)
+		}
+	}
+}
+
+func TestRateLimiter_Reserve(t *testing.T) {
+	l := newRateLimiter(4, http.DefaultTransport).(*rateLimiter)
+	now := time.Now()
+
+	// requests at once wait for their slots
+	assert.Equal(t, time.Duration(0), l.reserve(now))
+	assert.Equal(t, 250*time.Millisecond, l.reserve(now))
+	assert.Equal(t, 500*time.Millisecond, l.reserve(now))
+
+	// slots are not accumulated while client is idle
+	now = now.Add(time.Minute)
+	assert.Equal(t, time.Duration(0), l.reserve(now))
+	assert.Equal(t, 150*time.Millisecond, l.reserve(now.Add(100*time.Millisecond)))
+
+	assert.Equal(t, http.DefaultTransport, newRateLimiter(0, http.DefaultTransport))
+}
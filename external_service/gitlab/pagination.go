@@ -0,0 +1,26 @@
+package gitlab_
+
+import (
+	"github.com/xanzy/go-gitlab"
+)
+
+// maximum page size of gitlab api
+const perPage = 100
+
+// listPage loads one page of a list call, items are collected by the caller
+type listPage func(opt gitlab.ListOptions) (*gitlab.Response, error)
+
+// paginate calls list for every page until the last one, gitlab returns 20 items by default
+func paginate(list listPage) error {
+	opt := gitlab.ListOptions{Page: 1, PerPage: perPage}
+	for {
+		resp, err := list(opt)
+		if err != nil {
+			return err
+		}
+		if resp == nil || resp.NextPage == 0 {
+			return nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
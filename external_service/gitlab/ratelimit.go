@@ -0,0 +1,51 @@
+package gitlab_
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// rateLimiter is http transport which spaces out requests to gitlab evenly,
+// all calls of the client share it, so concurrent workers do not exceed the limit together
+type rateLimiter struct {
+	next     http.RoundTripper
+	interval time.Duration
+
+	mu sync.Mutex
+	// time of the next free slot
+	at time.Time
+}
+
+// newRateLimiter limits next transport to rps requests per second, zero means no limit
+func newRateLimiter(rps float64, next http.RoundTripper) http.RoundTripper {
+	if rps <= 0 {
+		return next
+	}
+	return &rateLimiter{next: next, interval: time.Duration(float64(time.Second) / rps)}
+}
+
+// reserve takes the next free slot and returns how long request waits for it
+func (l *rateLimiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.at.Before(now) {
+		l.at = now
+	}
+	wait := l.at.Sub(now)
+	l.at = l.at.Add(l.interval)
+	return wait
+}
+
+func (l *rateLimiter) RoundTrip(req *http.Request) (*http.Response, error) {
+	if wait := l.reserve(time.Now()); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+	return l.next.RoundTrip(req)
+}
@@ -0,0 +1,72 @@
+package telegram
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+// messages are sent in HTML parse mode, so user data must be escaped
+// https://core.telegram.org/bots/api#html-style
+
+// Escape makes text safe to put into HTML message
+func Escape(text string) string {
+	return html.EscapeString(text)
+}
+
+// Link renders hyperlink with the text, url itself is shown if text is empty
+func Link(url, text string) string {
+	if text == "" {
+		text = url
+	}
+	return fmt.Sprintf(`<a href="%s">%s</a>`, Escape(url), Escape(text))
+}
+
+// Mention renders @username, users without username are mentioned by telegram id with the name as text
+func Mention(username, id, name string) string {
+	if username != "" {
+		return "@" + Escape(username)
+	}
+	if name == "" {
+		name = id
+	}
+	return fmt.Sprintf(`<a href="tg://user?id=%s">%s</a>`, Escape(id), Escape(name))
+}
+
+func Bold(text string) string {
+	return "<b>" + Escape(text) + "</b>"
+}
+
+// Pre renders preformatted block of monospace text
+func Pre(text string) string {
+	return "<pre>" + Escape(text) + "</pre>"
+}
+
+// Table renders rows as preformatted table with columns aligned by the widest cell
+func Table(rows [][]string) string {
+	var widths []int
+	for _, row := range rows {
+		for i, cell := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			if n := utf8.RuneCountInString(cell); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+
+	lines := make([]string, 0, len(rows))
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = cell
+			if i < len(row)-1 {
+				cells[i] += strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell))
+			}
+		}
+		lines = append(lines, strings.Join(cells, "  "))
+	}
+	return Pre(strings.Join(lines, "\n"))
+}
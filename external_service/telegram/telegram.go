@@ -1,12 +1,16 @@
 package telegram
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	ce "tgj-bot/custom_errors"
 
@@ -15,6 +19,169 @@ import (
 	"github.com/go-telegram-bot-api/telegram-bot-api"
 )
 
+// telegram api error on editing message with the same content
+const messageNotModified = "message is not modified"
+
+// MaxMessageLength is telegram limit of message text length in characters
+const MaxMessageLength = 4096
+
+// SplitMessage splits HTML text into parts not longer than limit characters.
+// Text is split at line boundaries, lines longer than limit are split at spaces or cut, never inside of tag or entity.
+// Tags open at the cut are closed at the end of the part and reopened in the next one, so every part is valid HTML
+func SplitMessage(msg string, limit int) (parts []string) {
+	if utf8.RuneCountInString(msg) <= limit {
+		return []string{msg}
+	}
+	tokens := tokenizeHTML(msg)
+	// tags open at the start of the part
+	var open []htmlToken
+	for start := 0; start < len(tokens); {
+		size := tokensSize(open)
+		stack := open
+		end := start
+		// the last line break, space and cut which doesn't leave empty tag, with tags open there
+		lineEnd, wordEnd, cutEnd := start, start, start
+		var lineStack, wordStack, cutStack []htmlToken
+		for ; end < len(tokens); end++ {
+			t := tokens[end]
+			next := pushTag(stack, t)
+			if end > start && size+t.size+closingSize(next) > limit {
+				break
+			}
+			size += t.size
+			stack = next
+			if !t.isOpening() {
+				cutEnd, cutStack = end+1, stack
+			}
+			switch t.text {
+			case "\n":
+				lineEnd, lineStack = end+1, stack
+			case " ":
+				wordEnd, wordStack = end+1, stack
+			}
+		}
+		if end < len(tokens) {
+			switch {
+			case lineEnd > start:
+				end, stack = lineEnd, lineStack
+			case wordEnd > start:
+				end, stack = wordEnd, wordStack
+			case cutEnd > start:
+				end, stack = cutEnd, cutStack
+			}
+		}
+
+		var part strings.Builder
+		for _, t := range open {
+			part.WriteString(t.text)
+		}
+		for _, t := range tokens[start:end] {
+			part.WriteString(t.text)
+		}
+		for k := len(stack) - 1; k >= 0; k-- {
+			part.WriteString(stack[k].closing())
+		}
+		parts = append(parts, part.String())
+		open, start = stack, end
+	}
+	return
+}
+
+// htmlToken is a tag, an entity or a single character of HTML text
+type htmlToken struct {
+	text string
+	size int
+	// name of the tag, empty for text
+	tag string
+}
+
+func (t htmlToken) isOpening() bool {
+	return t.tag != "" && !strings.HasPrefix(t.text, "</")
+}
+
+func (t htmlToken) closing() string {
+	return "</" + t.tag + ">"
+}
+
+func tokenizeHTML(msg string) (tokens []htmlToken) {
+	for len(msg) > 0 {
+		n := 0
+		switch msg[0] {
+		case '<':
+			n = strings.IndexByte(msg, '>') + 1
+		case '&':
+			if k := strings.IndexByte(msg, ';'); k > 1 && k < 10 && !strings.ContainsAny(msg[1:k], " <&\n") {
+				n = k + 1
+			}
+		}
+		if n <= 0 {
+			_, n = utf8.DecodeRuneInString(msg)
+		}
+		t := htmlToken{text: msg[:n], size: utf8.RuneCountInString(msg[:n])}
+		if msg[0] == '<' {
+			if name := strings.FieldsFunc(t.text, isTagDelimiter); len(name) > 0 {
+				t.tag = strings.ToLower(name[0])
+			}
+		}
+		tokens = append(tokens, t)
+		msg = msg[n:]
+	}
+	return
+}
+
+func isTagDelimiter(r rune) bool {
+	return r == '<' || r == '/' || r == '>' || unicode.IsSpace(r)
+}
+
+// pushTag returns tags open after the token, stack of the caller is not changed
+func pushTag(stack []htmlToken, t htmlToken) []htmlToken {
+	switch {
+	case t.tag == "":
+		return stack
+	case t.isOpening():
+		return append(stack[:len(stack):len(stack)], t)
+	case len(stack) > 0 && stack[len(stack)-1].tag == t.tag:
+		return stack[: len(stack)-1 : len(stack)-1]
+	}
+	return stack
+}
+
+func tokensSize(tokens []htmlToken) (size int) {
+	for _, t := range tokens {
+		size += t.size
+	}
+	return
+}
+
+func closingSize(stack []htmlToken) (size int) {
+	for _, t := range stack {
+		size += len(t.closing())
+	}
+	return
+}
+
+// RetryAfter returns delay requested by telegram on rate limit error, zero for other errors
+func RetryAfter(err error) time.Duration {
+	if e, ok := err.(tgbotapi.Error); ok && e.RetryAfter > 0 {
+		return time.Duration(e.RetryAfter) * time.Second
+	}
+	return 0
+}
+
+// IsForbidden reports whether bot can't write to the chat at all,
+// e.g. user blocked the bot or never started private chat with it
+func IsForbidden(err error) bool {
+	e, ok := err.(tgbotapi.Error)
+	return ok && strings.HasPrefix(e.Message, "Forbidden")
+}
+
+// IsBadRequest reports whether telegram rejected the request itself, e.g. can't parse entities of the message,
+// so it fails the same way on retry
+func IsBadRequest(err error) bool {
+	e, ok := err.(tgbotapi.Error)
+	return ok && strings.HasPrefix(e.Message, "Bad Request")
+}
+
 type TgConfig struct {
 	Token         string `json:"token"`
 	UpdateTimeout int    `json:"update_timeout"`
@@ -26,6 +193,8 @@ type Client struct {
 	Bot     *tgbotapi.BotAPI
 	Updates tgbotapi.UpdatesChannel
 	ChatID  int64
+
+	updateTimeout int
 }
 
 func RunBot(cfg TgConfig) (tgClient Client, err error) {
@@ -40,21 +209,85 @@ func RunBot(cfg TgConfig) (tgClient Client, err error) {
 	tgClient.Bot.Debug = true
 	log.Printf("Authorized on account %s", tgClient.Bot.Self.UserName)
 
+	tgClient.updateTimeout = cfg.UpdateTimeout
+	tgClient.ChatID = cfg.ChatID
+
+	return
+}
+
+// StartUpdates starts long polling of updates, telegram allows only one consumer of updates per bot token
+func (c *Client) StartUpdates() (err error) {
 	u := tgbotapi.NewUpdate(0)
-	u.Timeout = cfg.UpdateTimeout
+	u.Timeout = c.updateTimeout
 
-	tgClient.Updates, err = tgClient.Bot.GetUpdatesChan(u)
+	c.Updates, err = c.Bot.GetUpdatesChan(u)
 	if err != nil {
-		return tgClient, errors.New("Update channel err: " + err.Error())
+		return errors.New("Update channel err: " + err.Error())
 	}
-	tgClient.ChatID = cfg.ChatID
+	return nil
+}
 
-	return
+// StopUpdates stops long polling, so updates can be consumed by another replica
+func (c *Client) StopUpdates() {
+	c.Bot.StopReceivingUpdates()
+}
+
+// Send sends HTML message to the chat, long message should be split by SplitMessage before.
+// Returns id of the sent message
+func (c *Client) Send(chatID int64, msg string, replyMarkup interface{}) (messageID int, err error) {
+	m := tgbotapi.NewMessage(chatID, msg)
+	m.ParseMode = tgbotapi.ModeHTML
+	m.ReplyMarkup = replyMarkup
+	sent, err := c.Bot.Send(m)
+	if err != nil {
+		log.Printf("Couldn't send message to %d '%v': %v", chatID, msg, err)
+		return 0, err
+	}
+	return sent.MessageID, nil
+}
+
+// EditMessage replaces text of already sent message, nil keyboard removes buttons
+func (c *Client) EditMessage(chatID int64, messageID int, msg string, keyboard *tgbotapi.InlineKeyboardMarkup) error {
+	m := tgbotapi.NewEditMessageText(chatID, messageID, msg)
+	m.ParseMode = tgbotapi.ModeHTML
+	m.ReplyMarkup = keyboard
+	if _, err := c.Bot.Send(m); err != nil {
+		if strings.Contains(err.Error(), messageNotModified) {
+			return nil
+		}
+		log.Printf("Couldn't edit message %d '%v': %v", messageID, msg, err)
+		return err
+	}
+	return nil
+}
+
+// scopes of bot commands menu
+const (
+	ScopeGroupChats   = "all_group_chats"
+	ScopePrivateChats = "all_private_chats"
+)
+
+type BotCommand struct {
+	Command     string `json:"command"`
+	Description string `json:"description"`
+}
+
+// SetCommands replaces bot commands menu shown to users in the chats of the scope
+func (c *Client) SetCommands(commands []BotCommand, scope string) error {
+	data, err := json.Marshal(commands)
+	if err != nil {
+		return err
+	}
+	v := url.Values{}
+	v.Add("commands", string(data))
+	v.Add("scope", fmt.Sprintf(`{"type":%q}`, scope))
+	_, err = c.Bot.MakeRequest("setMyCommands", v)
+	return err
 }
 
-func (c *Client) SendMessage(msg string) {
-	if m, err := c.Bot.Send(tgbotapi.NewMessage(c.ChatID, msg)); err != nil {
-		log.Printf("Couldn't send message '%v': %v", m, err)
+func (c *Client) AnswerCallback(callbackID, text string) {
+	if _, err := c.Bot.AnswerCallbackQuery(tgbotapi.NewCallback(callbackID, text)); err != nil {
+		log.Printf("Couldn't answer callback '%v': %v", callbackID, err)
 	}
 	return
 }
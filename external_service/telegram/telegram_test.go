@@ -1 +1,118 @@
 package telegram
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitMessage(t *testing.T) {
+	testCases := []struct {
+		msg   string
+		limit int
+		parts []string
+	}{
+		{"", 10, []string{""}},
+		{"short", 10, []string{"short"}},
+		{"line1\nline2\nline3", 12, []string{"line1\nline2\n", "line3"}},
+		{"line1\nline2\nline3", 6, []string{"line1\n", "line2\n", "line3"}},
+		{"verylongline\nok", 5, []string{"veryl", "ongli", "ne\nok"}},
+		{"привет\nмир", 7, []string{"привет\n", "мир"}},
+		{"a &amp; b\nc", 5, []string{"a ", "&amp;", " b\nc"}},
+	}
+
+	for i, tc := range testCases {
+		parts := SplitMessage(tc.msg, tc.limit)
+		if !assert.Equal(t, tc.parts, parts) {
+			t.Fatalf("failed at index %d", i)
+		}
+		if strings.Join(parts, "") != tc.msg {
+			t.Fatalf("failed at index %d: text is lost", i)
+		}
+	}
+}
+
+func TestSplitMessage_HTML(t *testing.T) {
+	testCases := []struct {
+		msg   string
+		limit int
+		parts []string
+	}{
+		{"<pre>ab\ncd</pre>", 14, []string{"<pre>ab\n</pre>", "<pre>cd</pre>"}},
+		{"<b>x</b>\n<pre>abcdef</pre>", 15, []string{"<b>x</b>\n", "<pre>abcd</pre>", "<pre>ef</pre>"}},
+		{`x <a href="u">link</a>`, 20, []string{"x ", `<a href="u">link</a>`}},
+		{`<a href="u">link</a> y`, 18, []string{`<a href="u">li</a>`, `<a href="u">nk</a>`, " y"}},
+	}
+
+	for i, tc := range testCases {
+		parts := SplitMessage(tc.msg, tc.limit)
+		if !assert.Equal(t, tc.parts, parts) {
+			t.Fatalf("failed at index %d", i)
+		}
+		for _, part := range parts {
+			if utf8.RuneCountInString(part) > tc.limit {
+				t.Fatalf("failed at index %d: part %q is too long", i, part)
+			}
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	testCases := []struct {
+		err   error
+		delay time.Duration
+	}{
+		{nil, 0},
+		{errors.New("network is unreachable"), 0},
+		{tgbotapi.Error{Message: "Too Many Requests: retry after 5", ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 5}}, 5 * time.Second},
+		{tgbotapi.Error{Message: "Bad Request: chat not found"}, 0},
+	}
+
+	for i, tc := range testCases {
+		if delay := RetryAfter(tc.err); delay != tc.delay {
+			t.Fatalf("failed at index %d: got %v", i, delay)
+		}
+	}
+}
+
+func TestIsBadRequest(t *testing.T) {
+	assert.True(t, IsBadRequest(tgbotapi.Error{Message: "Bad Request: can't parse entities"}))
+	assert.False(t, IsBadRequest(tgbotapi.Error{Message: "Forbidden: bot was blocked by the user"}))
+	assert.False(t, IsBadRequest(errors.New("Bad Request")))
+	assert.False(t, IsBadRequest(nil))
+}
+
+func TestIsForbidden(t *testing.T) {
+	assert.True(t, IsForbidden(tgbotapi.Error{Message: "Forbidden: bot was blocked by the user"}))
+	assert.False(t, IsForbidden(tgbotapi.Error{Message: "Too Many Requests: retry after 5"}))
+	assert.False(t, IsForbidden(errors.New("Forbidden")))
+	assert.False(t, IsForbidden(nil))
+}
+
+func TestLink(t *testing.T) {
+	assert.Equal(t, `<a href="https://gitlab.com/mr/1?a=1&amp;b=2">!1 Fix &lt;login&gt; [NC-42]</a>`,
+		Link("https://gitlab.com/mr/1?a=1&b=2", "!1 Fix <login> [NC-42]"))
+	assert.Equal(t, `<a href="https://gitlab.com/mr/1">https://gitlab.com/mr/1</a>`, Link("https://gitlab.com/mr/1", ""))
+}
+
+func TestTable(t *testing.T) {
+	rows := [][]string{
+		{"user", "role", "reviews"},
+		{"john_doe", "dev", "3"},
+		{"ivan", "lead", "10"},
+	}
+	expected := "<pre>user      role  reviews\njohn_doe  dev   3\nivan      lead  10</pre>"
+	assert.Equal(t, expected, Table(rows))
+	assert.Equal(t, "<pre>&lt;b&gt;</pre>", Table([][]string{{"<b>"}}))
+}
+
+func TestMention(t *testing.T) {
+	assert.Equal(t, "@john_doe", Mention("john_doe", "42", "John"))
+	assert.Equal(t, `<a href="tg://user?id=42">John &lt;Doe&gt;</a>`, Mention("", "42", "John <Doe>"))
+	assert.Equal(t, `<a href="tg://user?id=42">42</a>`, Mention("", "42", ""))
+}
@@ -21,6 +21,44 @@ const (
 	ReviewedLabel = "reviewed"
 )
 
+// gitlab merge request states
+const (
+	StateOpened = "opened"
+	StateClosed = "closed"
+	StateLocked = "locked"
+	StateMerged = "merged"
+)
+
+// gitlab pipeline statuses, empty status means there is no pipeline
+const (
+	PipelineCreated  = "created"
+	PipelinePending  = "pending"
+	PipelineRunning  = "running"
+	PipelineSuccess  = "success"
+	PipelineFailed   = "failed"
+	PipelineCanceled = "canceled"
+	PipelineSkipped  = "skipped"
+	PipelineManual   = "manual"
+)
+
+func IsPipelineFailed(status string) bool {
+	return status == PipelineFailed || status == PipelineCanceled
+}
+
+// IsPipelinePassed reports whether pipeline is finished without failure or there is no pipeline
+func IsPipelinePassed(status string) bool {
+	switch status {
+	case "", PipelineSuccess, PipelineSkipped, PipelineManual:
+		return true
+	}
+	return false
+}
+
+// IsFinalState reports whether mr is merged or closed, locked mr is still being merged
+func IsFinalState(state string) bool {
+	return state == StateMerged || state == StateClosed
+}
+
 var jiraRegExp = regexp.MustCompile(`\[NC-([0-9]+)\]*`)
 
 type UserBrief struct {
@@ -38,6 +76,7 @@ type User struct {
 	UserBrief
 	JiraID   string
 	IsActive bool
+	IsAdmin  bool
 }
 
 type UserList []User
@@ -67,6 +106,75 @@ func (ups UsersPayload) GetN(num int, role Role) (res UsersPayload, err error) {
 	return
 }
 
+type NotifyMode string
+
+const (
+	NotifyGroup = NotifyMode("group")
+	NotifyDM    = NotifyMode("dm")
+	NotifyBoth  = NotifyMode("both")
+)
+
+var ValidNotifyModes = [...]NotifyMode{NotifyGroup, NotifyDM, NotifyBoth}
+
+func IsValidNotifyMode(m NotifyMode) bool {
+	for _, mode := range ValidNotifyModes {
+		if mode == m {
+			return true
+		}
+	}
+	return false
+}
+
+// UserSettings are personal notification preferences
+type UserSettings struct {
+	UserID     int
+	NotifyMode NotifyMode
+	// quiet hours [QuietFrom, QuietTo), disabled if equal
+	QuietFrom int
+	QuietTo   int
+	// user started private chat with the bot, so it can send direct messages
+	HasPrivateChat bool
+}
+
+func (s UserSettings) IsQuiet(t time.Time) bool {
+	if s.QuietFrom == s.QuietTo {
+		return false
+	}
+	h := t.Hour()
+	if s.QuietFrom < s.QuietTo {
+		return h >= s.QuietFrom && h < s.QuietTo
+	}
+	// quiet hours over midnight, e.g. 22-8
+	return h >= s.QuietFrom || h < s.QuietTo
+}
+
+// QuietEnd returns time when quiet hours are over, t if it is not in quiet hours
+func (s UserSettings) QuietEnd(t time.Time) time.Time {
+	if !s.IsQuiet(t) {
+		return t
+	}
+	end := time.Date(t.Year(), t.Month(), t.Day(), s.QuietTo, 0, 0, 0, t.Location())
+	if !end.After(t) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return end
+}
+
+// Delivery returns where reminders should be sent at the time and when direct message should be delivered.
+// Group chat is used as fallback when direct message is not possible. During quiet hours direct message
+// is deferred till their end, user in both mode gets only group message then
+func (s UserSettings) Delivery(t time.Time) (group, dm bool, dmAt time.Time) {
+	dm = s.HasPrivateChat && s.NotifyMode != NotifyGroup
+	group = s.NotifyMode != NotifyDM || !dm
+	if dm && s.NotifyMode == NotifyBoth && s.IsQuiet(t) {
+		dm = false
+	}
+	if dm {
+		dmAt = s.QuietEnd(t)
+	}
+	return
+}
+
 type Role string
 
 var ValidRoles = [...]Role{Developer, Lead}
@@ -89,6 +197,50 @@ type MR struct {
 	JiraID       int
 	JiraPriority int
 	JiraStatus   int
+	// telegram message with live review status
+	MessageID int
+	// gitlab title, shown as link text
+	Title string
+	// all reviewers approved, mr is still open until it is merged or closed in gitlab
+	IsReviewed bool
+	// last seen head commit, new commits may reset approvals
+	HeadSHA string
+	// gitlab state, IsClosed is set for merged and closed mrs
+	State    string
+	MergedAt int64
+	ClosedAt int64
+	// draft mr waits to be ready for review, reviewers are not assigned yet
+	IsPending bool
+	// reviewer overrides of /mr, applied when pending mr is assigned
+	PendingArgs string
+	// status of head pipeline
+	PipelineStatus string
+	// unix time when mr became stale, zero if it is active
+	StaleAt int64
+	// commit time of HeadSHA, zero if it is not known yet
+	HeadCommittedAt int64
+	// stale label is added to mr
+	IsStaleLabeled bool
+}
+
+func (mr MR) IsStale() bool {
+	return mr.StaleAt != 0
+}
+
+// IsAbandoned reports whether mr was closed without merge
+func (mr MR) IsAbandoned() bool {
+	return mr.State == StateClosed
+}
+
+// SetState applies gitlab state, reopened mr is tracked again
+func (mr *MR) SetState(state string, mergedAt, closedAt int64) {
+	mr.State = state
+	mr.IsClosed = IsFinalState(state)
+	mr.MergedAt = mergedAt
+	mr.ClosedAt = closedAt
+	if !mr.IsClosed {
+		mr.ClosedAt = 0
+	}
 }
 
 func (mr *MR) ExtractJiraID(title string) {
@@ -115,7 +267,141 @@ type Review struct {
 	UserID      int
 	IsApproved  bool
 	IsCommented bool
+	IsTaken     bool
 	UpdatedAt   int64
+	// approval was reset by new commits, emoji awarded before do not approve
+	ResetAt int64
+}
+
+// ReviewChange is a review moved from one reviewer to another
+type ReviewChange struct {
+	ID         int
+	MrID       int
+	FromUserID int
+	ToUserID   int
+	Reason     string
+	// telegram id of user who moved the review, empty if moved by the bot
+	ChangedBy string
+	CreatedAt int64
+}
+
+// Thread is a gitlab discussion of mr, users are gitlab ids
+type Thread struct {
+	MrID         int
+	DiscussionID string
+	// first note, thread is linked to it
+	NoteID       int
+	OpenedBy     int
+	LastNoteBy   int
+	Participants []int
+	IsResolvable bool
+	IsResolved   bool
+	// user expected to reply, 0 if nobody
+	AwaitingID int
+	// unix time of the last note, it is not stored
+	LastNoteAt int64
+}
+
+// Awaiting returns user who should reply: mr author answers threads of reviewers,
+// thread opener answers after the author replied
+func (t Thread) Awaiting(authorID int) int {
+	if !t.IsResolvable || t.IsResolved || authorID == 0 {
+		return 0
+	}
+	if t.LastNoteBy != authorID {
+		return authorID
+	}
+	if t.OpenedBy != authorID {
+		return t.OpenedBy
+	}
+	return 0
+}
+
+// CommentedUsers reports for every participant of threads whether the participant has unresolved threads
+func CommentedUsers(threads []Thread) map[int]bool {
+	users := make(map[int]bool)
+	for _, t := range threads {
+		for _, id := range t.Participants {
+			users[id] = users[id] || !t.IsResolved
+		}
+	}
+	return users
+}
+
+type EscalationStep int
+
+const (
+	EscalationNone = EscalationStep(iota)
+	EscalationReminder
+	EscalationLead
+	EscalationReallocate
+)
+
+// StalledReview is a review waiting for reviewer with its escalation progress.
+// Escalation starts over when review is updated.
+type StalledReview struct {
+	Review
+	JiraPriority   int
+	PipelineStatus string
+	Step           EscalationStep
+}
+
+// PendingRegistration waits until user proves ownership of gitlab account with the code
+type PendingRegistration struct {
+	TelegramID       string
+	TelegramUsername string
+	GitlabID         int
+	GitlabName       string
+	Role             Role
+	Code             string
+	ExpiresAt        int64
+	CreatedAt        int64
+}
+
+// AuditRecord is a command denied to the user
+type AuditRecord struct {
+	ID               int
+	TelegramID       string
+	TelegramUsername string
+	Command          string
+	Args             string
+	Reason           string
+	CreatedAt        int64
+}
+
+type OutboxStatus string
+
+const (
+	OutboxPending = OutboxStatus("pending")
+	OutboxSent    = OutboxStatus("sent")
+	OutboxFailed  = OutboxStatus("failed")
+)
+
+// OutboxMessage is telegram message waiting for delivery.
+// Messages with the same non-empty DedupKey are enqueued only once,
+// non-zero EditMessageID replaces text of already sent message instead of sending new one
+type OutboxMessage struct {
+	ID            int
+	ChatID        int64
+	Text          string
+	ReplyMarkup   string
+	DedupKey      string
+	Status        OutboxStatus
+	Attempts      int
+	NextAttemptAt int64
+	LastError     string
+	MessageID     int
+	EditMessageID int
+	CreatedAt     int64
+	SentAt        int64
+}
+
+type Reviewer struct {
+	UserBrief
+	IsApproved  bool
+	IsCommented bool
+	IsTaken     bool
+	ResetAt     int64
 }
 
 func GetGitlabID(mrURL string) (int, error) {
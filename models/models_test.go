@@ -1 +1,142 @@
 package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUserSettings_IsQuiet(t *testing.T) {
+	tests := []struct {
+		from, to, hour int
+		exp            bool
+	}{
+		{0, 0, 3, false},
+		{9, 18, 8, false},
+		{9, 18, 9, true},
+		{9, 18, 18, false},
+		{22, 8, 23, true},
+		{22, 8, 3, true},
+		{22, 8, 8, false},
+		{22, 8, 12, false},
+	}
+
+	for index, item := range tests {
+		s := UserSettings{QuietFrom: item.from, QuietTo: item.to}
+		now := time.Date(2019, 10, 1, item.hour, 30, 0, 0, time.Local)
+		if s.IsQuiet(now) != item.exp {
+			t.Fatalf("failed at index %d", index)
+		}
+	}
+}
+
+func TestUserSettings_Delivery(t *testing.T) {
+	now := time.Date(2019, 10, 1, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		s        UserSettings
+		expGroup bool
+		expDM    bool
+		expDMAt  time.Time
+	}{
+		{UserSettings{NotifyMode: NotifyGroup, HasPrivateChat: true}, true, false, time.Time{}},
+		{UserSettings{NotifyMode: NotifyDM, HasPrivateChat: true}, false, true, now},
+		{UserSettings{NotifyMode: NotifyBoth, HasPrivateChat: true}, true, true, now},
+		// no private chat with bot
+		{UserSettings{NotifyMode: NotifyDM}, true, false, time.Time{}},
+		// quiet hours defer direct message, team chat is not used instead
+		{UserSettings{NotifyMode: NotifyDM, HasPrivateChat: true, QuietFrom: 11, QuietTo: 13}, false, true, now.Add(time.Hour)},
+		{UserSettings{NotifyMode: NotifyDM, HasPrivateChat: true, QuietFrom: 11, QuietTo: 9}, false, true, now.Add(21 * time.Hour)},
+		{UserSettings{NotifyMode: NotifyBoth, HasPrivateChat: true, QuietFrom: 11, QuietTo: 13}, true, false, time.Time{}},
+		{UserSettings{NotifyMode: NotifyGroup, HasPrivateChat: true, QuietFrom: 11, QuietTo: 13}, true, false, time.Time{}},
+	}
+
+	for index, item := range tests {
+		group, dm, dmAt := item.s.Delivery(now)
+		if group != item.expGroup || dm != item.expDM || !dmAt.Equal(item.expDMAt) {
+			t.Fatalf("failed at index %d", index)
+		}
+	}
+}
+
+func TestThread_Awaiting(t *testing.T) {
+	const author, reviewer, other = 1, 2, 3
+	tests := []struct {
+		thread Thread
+		exp    int
+	}{
+		{Thread{OpenedBy: reviewer, LastNoteBy: reviewer, IsResolvable: true}, author},
+		{Thread{OpenedBy: reviewer, LastNoteBy: other, IsResolvable: true}, author},
+		{Thread{OpenedBy: reviewer, LastNoteBy: author, IsResolvable: true}, reviewer},
+		{Thread{OpenedBy: author, LastNoteBy: author, IsResolvable: true}, 0},
+		{Thread{OpenedBy: author, LastNoteBy: reviewer, IsResolvable: true}, author},
+		{Thread{OpenedBy: reviewer, LastNoteBy: reviewer, IsResolvable: true, IsResolved: true}, 0},
+		{Thread{OpenedBy: reviewer, LastNoteBy: reviewer}, 0},
+	}
+
+	for index, item := range tests {
+		if item.thread.Awaiting(author) != item.exp {
+			t.Fatalf("failed at index %d", index)
+		}
+	}
+}
+
+func TestCommentedUsers(t *testing.T) {
+	threads := []Thread{
+		{Participants: []int{1, 2}, IsResolved: true},
+		{Participants: []int{2, 3}},
+	}
+	exp := map[int]bool{1: false, 2: true, 3: true}
+	users := CommentedUsers(threads)
+	if len(users) != len(exp) {
+		t.Fatalf("unexpected users %v", users)
+	}
+	for id, commented := range exp {
+		if users[id] != commented {
+			t.Fatalf("failed for user %d", id)
+		}
+	}
+}
+
+func TestMR_SetState(t *testing.T) {
+	tests := []struct {
+		state                    string
+		mergedAt, closedAt       int64
+		isClosed, isAbandoned    bool
+		expMergedAt, expClosedAt int64
+	}{
+		{StateClosed, 0, 100, true, true, 0, 100},
+		// reopened
+		{StateOpened, 0, 100, false, false, 0, 0},
+		{StateLocked, 0, 0, false, false, 0, 0},
+		{StateMerged, 200, 0, true, false, 200, 0},
+	}
+
+	for index, item := range tests {
+		var mr MR
+		mr.SetState(item.state, item.mergedAt, item.closedAt)
+		if mr.State != item.state || mr.IsClosed != item.isClosed || mr.IsAbandoned() != item.isAbandoned ||
+			mr.MergedAt != item.expMergedAt || mr.ClosedAt != item.expClosedAt {
+			t.Fatalf("failed at index %d", index)
+		}
+	}
+}
+
+func TestPipelineStatus(t *testing.T) {
+	tests := []struct {
+		status           string
+		passed, isFailed bool
+	}{
+		{"", true, false},
+		{PipelineSuccess, true, false},
+		{PipelineManual, true, false},
+		{PipelineRunning, false, false},
+		{PipelinePending, false, false},
+		{PipelineFailed, false, true},
+		{PipelineCanceled, false, true},
+	}
+
+	for index, item := range tests {
+		if IsPipelinePassed(item.status) != item.passed || IsPipelineFailed(item.status) != item.isFailed {
+			t.Fatalf("failed at index %d", index)
+		}
+	}
+}
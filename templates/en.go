@@ -0,0 +1,139 @@
+package templates
+
+var en = map[string]string{
+	Help: `{{range .}}/{{.Name}}{{if .Args}} {{esc .Args}}{{end}} - {{esc .Description}}{{if .IsAdmin}} (admins only){{end}}
+{{end}}`,
+	Success: `Success! 👍`,
+	MrStatus: `New merge request 🚀
+{{range .Reviewers}}{{.Emoji}} {{.Mention}}
+{{end}}-----------------------
+{{link .URL .Title}}{{if eq .State "merged"}}
+🎉 merged{{else if eq .State "closed"}}
+🚫 closed{{else if eq .State "locked"}}
+🔒 locked{{else if .IsReviewed}}
+👍 reviewed{{end}}`,
+	MrParty: `Review party:
+{{range .Reviewers}}{{.Emoji}} {{esc .Username}}
+{{end}}-----------------------
+{{link .URL .Title}}`,
+	NewReview: `New review:
+{{.Mention}}
+-----------------------
+{{link .URL .Title}}`,
+	DailyGreeting: `🚀 Daily notification 🌞`,
+	DailyUser: `-----------------------
+<b>{{.Mention}}</b> {{.Emoji}}
+{{range .Tasks}}{{.Emoji}} {{link .URL .Title}}
+{{end}}{{range .Reviews}}{{.Emoji}} {{link .URL .Title}}
+{{end}}{{range .Threads}}{{.Emoji}} {{link .URL .Title}}
+{{end}}`,
+	DailyDirect: `{{template "daily_greeting"}}
+{{range .Tasks}}{{.Emoji}} {{link .URL .Title}}
+{{end}}{{range .Reviews}}{{.Emoji}} {{link .URL .Title}}
+{{end}}{{range .Threads}}{{.Emoji}} {{link .URL .Title}}
+{{end}}`,
+	Praise: `It is so nice to open a reviewed project)
+I can see you tried really hard!
+Thank you for the reviews!
+Good job, keep it up!
+dude, good job`,
+	Motivate: `Just Do IT
+"It's not the load that breaks you down, it's the way you carry it", — Lou Holtz
+"I'm not afraid to die, I'm afraid not to have tried", — Jay-Z
+"Fake it until you make it!", — Brian Tracy
+"Always render more and better service than is expected of you", — Og Mandino`,
+	MoveTaskToQA:   `✈️ {{.Mention}}, please move task to QA: {{link .URL .Title}}`,
+	ButtonTake:     `Take it`,
+	ButtonReassign: `Can't review → reassign`,
+	ButtonSnooze:   `Snooze 1 day`,
+	ButtonOpen:     `Open MR`,
+	AnswerTake:     `The review is yours 👍`,
+	AnswerSnooze:   `Snoozed for 1 day 😴`,
+	StartDone:      `Now I can send you reminders here. Use /settings to choose where to get them`,
+	Settings: `Notifications: {{.NotifyMode}}
+Quiet hours: {{if .Quiet}}{{.Quiet}}{{else}}off{{end}}
+{{if and (not .HasPrivateChat) (ne .NotifyMode "group")}}Send /start to me in private chat to get direct messages, until then reminders go to the team chat
+{{end}}`,
+	ReReview: `🔁{{range .Mentions}} {{.}}{{end}} new commits were pushed, please review again and re-add your 👍
+{{link .URL .Title}}`,
+	DraftPending:    `📝 {{link .URL .Title}} is a draft, I will assign reviewers when it is ready`,
+	PipelinePending: `⏳ pipeline of {{link .URL .Title}} has not passed yet, I will assign reviewers when it is green`,
+	PipelineFailed: `🔴 {{.Mention}} pipeline of your merge request failed, reviewers wait for the fix
+{{link .URL .Title}}`,
+	ReadyToMerge: `✅ {{.Mention}} your merge request is approved{{if .HasConflicts}}, but it has conflicts with the target branch, please resolve them before merge{{else if .IsAutoMerge}} and will be merged when pipeline succeeds{{else}} and ready to merge{{end}}
+{{link .URL .Title}}`,
+	StaleNudge: `💤 {{.Mention}} your merge request is idle for {{.Days}} business days or threads wait for your reply. Reviewers are not reminded about it until you push or reply{{if .CloseDays}}, it will be closed after {{.CloseDays}} days{{end}}
+{{link .URL .Title}}`,
+	EscalationReminder: `⏰ {{.Mention}} the review is waiting for you {{.Hours}}h
+{{link .URL .Title}}`,
+	EscalationLead: `🚨{{range .Leads}} {{.}}{{end}} review of {{.Mention}} is stalled for {{.Hours}}h
+{{link .URL .Title}}`,
+	EscalationReallocate: `♻️ review of {{.Mention}} is stalled for {{.Hours}}h, looking for another reviewer
+{{link .URL .Title}}`,
+	RegisterVerify: `To confirm that GitLab account {{esc .GitlabName}} is yours, {{if .IssueURL}}leave a comment with the code <code>{{esc .Code}}</code> on {{link .IssueURL ""}}{{else}}set your GitLab status to the code <code>{{esc .Code}}</code>{{end}} and send /verify within {{.Minutes}} minutes`,
+	Users: `Users:
+{{table .Rows}}`,
+	Whois: `{{.Mention}}{{if .IsAdmin}} (admin){{end}}
+GitLab: {{esc .GitlabName}} ({{.GitlabID}})
+Role: {{.Role}}
+Status: {{if .IsActive}}active{{else}}inactive{{end}}
+Open reviews: {{.Reviews}}`,
+	Stats: `Open reviews:
+{{table .Rows}}`,
+	Queue: `Review queue:
+{{range .}}{{.Emoji}} {{link .URL .Title}}{{range $i, $u := .Waiting}}{{if $i}},{{else}} —{{end}} {{esc $u}}{{end}}
+{{else}}empty 🎉
+{{end}}`,
+
+	CommandPrefix + "help":       `show this help`,
+	CommandPrefix + "register":   `register in the bot`,
+	CommandPrefix + "verify":     `finish registration after posting the code to gitlab`,
+	CommandPrefix + "mr":         `assign reviewers to merge request`,
+	CommandPrefix + "reassign":   `move review to another user, the best one if @to is not set`,
+	CommandPrefix + "swap":       `hand your review over to another user`,
+	CommandPrefix + "inactive":   `leave the project, reviews are reassigned`,
+	CommandPrefix + "active":     `return to the project`,
+	CommandPrefix + "stats":      `show open reviews of users`,
+	CommandPrefix + "queue":      `show open merge requests and reviewers they wait for`,
+	CommandPrefix + "users":      `list registered users`,
+	CommandPrefix + "whois":      `show gitlab account, role and reviews of the user`,
+	CommandPrefix + "role":       `change role of the user`,
+	CommandPrefix + "unregister": `remove user from the bot, reviews are reassigned`,
+	CommandPrefix + "settings":   `choose where to get reminders`,
+	CommandPrefix + "start":      `get reminders in this chat`,
+	CommandPrefix + "daily":      `send daily reminders now`,
+
+	ErrNotRegistered:         `{{if .}}@{{esc .}} is not registered{{else}}you are not registered yet, use /register in the team chat first{{end}}`,
+	ErrNotReviewer:           `{{if .}}@{{esc .}} is{{else}}you are{{end}} not a reviewer of this merge request`,
+	ErrNoReviewers:           `{{if .}}@{{esc .}}: {{end}}users for review not found`,
+	ErrReviewApproved:        `review already approved`,
+	ErrUserInactive:          `@{{esc .}} is inactive`,
+	ErrUserIsAuthor:          `@{{esc .}} is the author of merge request`,
+	ErrAlreadyReviewer:       `@{{esc .}} already reviews this merge request`,
+	ErrInvalidArgument:       `invalid argument {{esc .}}`,
+	ErrInvalidRole:           `role must be one of {{esc .}}`,
+	ErrInvalidNotifyMode:     `notify mode must be one of {{esc .}}`,
+	ErrInvalidQuietHours:     `invalid quiet hours {{esc .}}, expected range of hours like 22-8 or off`,
+	ErrInvalidMR:             `invalid merge request {{esc .}}`,
+	ErrMrNotOnReview:         `merge request {{esc .}} is not on review`,
+	ErrMrClosed:              `merge request is already closed`,
+	ErrMrTitle:               `mr title must have ticket number in square brackets without spaces inside. Example: [NC-1234]`,
+	ErrGetUsers:              `getting users failed`,
+	ErrAdminOnly:             `permission denied: the command is available to admins only`,
+	ErrChangeOtherUser:       `permission denied: only admins can change other users`,
+	ErrRegisterPrivRole:      `permission denied: only admins can register with role lead`,
+	ErrRegisterUsage:         `command requires gitlab id or name and optional role. For more information use /help`,
+	ErrMrUsage:               `command requires merge request url. For more information use /help`,
+	ErrOverrideUsage:         `reviewers must be given as @username, -@username to exclude, +dev or +lead for extra seat`,
+	ErrWhoisUsage:            `usage: /whois username`,
+	ErrRoleUsage:             `usage: /role username dev|lead`,
+	ErrSettingsUsage:         `usage: /settings [notify group|dm|both] [quiet from-to|off]. For more information use /help`,
+	ErrReassignUsage:         `command requires merge request url or id. For more information use /help`,
+	ErrNoPendingRegistration: `there is no registration to verify, use /register first`,
+	ErrCodeExpired:           `verification code expired, use /register again`,
+	ErrCodeNotFound:          `verification code not found in gitlab yet, try /verify a bit later`,
+	ErrFeatureUnavailable:    `this feature is not available`,
+	ErrCommandFailed:         `command /{{.}} failed`,
+	ErrReviewsNotMoved:       `reviews of merge requests {{esc .}} are not moved to other reviewers, use /reassign`,
+	ErrUnregisterReviews:     `user is not unregistered: reviews of merge requests {{esc .}} are not moved to other reviewers, use /reassign first`,
+}
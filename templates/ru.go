@@ -0,0 +1,139 @@
+package templates
+
+var ru = map[string]string{
+	Help: `{{range .}}/{{.Name}}{{if .Args}} {{esc .Args}}{{end}} - {{esc .Description}}{{if .IsAdmin}} (только для админов){{end}}
+{{end}}`,
+	Success: `Готово! 👍`,
+	MrStatus: `Новый merge request 🚀
+{{range .Reviewers}}{{.Emoji}} {{.Mention}}
+{{end}}-----------------------
+{{link .URL .Title}}{{if eq .State "merged"}}
+🎉 смержен{{else if eq .State "closed"}}
+🚫 закрыт{{else if eq .State "locked"}}
+🔒 заблокирован{{else if .IsReviewed}}
+👍 отревьюен{{end}}`,
+	MrParty: `Ревьюеры:
+{{range .Reviewers}}{{.Emoji}} {{esc .Username}}
+{{end}}-----------------------
+{{link .URL .Title}}`,
+	NewReview: `Новое ревью:
+{{.Mention}}
+-----------------------
+{{link .URL .Title}}`,
+	DailyGreeting: `🚀 Ежедневное напоминание 🌞`,
+	DailyUser: `-----------------------
+<b>{{.Mention}}</b> {{.Emoji}}
+{{range .Tasks}}{{.Emoji}} {{link .URL .Title}}
+{{end}}{{range .Reviews}}{{.Emoji}} {{link .URL .Title}}
+{{end}}{{range .Threads}}{{.Emoji}} {{link .URL .Title}}
+{{end}}`,
+	DailyDirect: `{{template "daily_greeting"}}
+{{range .Tasks}}{{.Emoji}} {{link .URL .Title}}
+{{end}}{{range .Reviews}}{{.Emoji}} {{link .URL .Title}}
+{{end}}{{range .Threads}}{{.Emoji}} {{link .URL .Title}}
+{{end}}`,
+	Praise: `Так приятно заходить в отревьюиный проект)
+Я вижу, что вы очень постарались!
+Спасибо за ревью и дай бог здоровья!
+Спасибо большое за вашу помощь в ревью!
+Хорошая работа, так держать!`,
+	Motivate: `Просто сделай это
+«Не ноша тянет вас вниз, а то, как вы ее несете», — Лу Хольц
+«Я не боюсь умереть, но я боюсь не попытаться», — Jay Z
+«Притворяйся, пока не получится! Делай вид, что ты настолько уверен в себе, насколько это необходимо, пока не обнаружишь, что так оно и есть», — Брайан Трейси
+«Всегда выкладывайся на полную. Что посеешь — то и пожнешь», — Ог Мандино`,
+	MoveTaskToQA:   `✈️ {{.Mention}}, переведи задачу в QA: {{link .URL .Title}}`,
+	ButtonTake:     `Беру`,
+	ButtonReassign: `Не могу → переназначить`,
+	ButtonSnooze:   `Отложить на день`,
+	ButtonOpen:     `Открыть MR`,
+	AnswerTake:     `Ревью за тобой 👍`,
+	AnswerSnooze:   `Отложено на день 😴`,
+	StartDone:      `Теперь я буду присылать напоминания сюда. Выбрать, куда их получать: /settings`,
+	Settings: `Уведомления: {{.NotifyMode}}
+Тихие часы: {{if .Quiet}}{{.Quiet}}{{else}}off{{end}}
+{{if and (not .HasPrivateChat) (ne .NotifyMode "group")}}Напиши мне /start в личном чате, чтобы получать личные сообщения, а пока напоминания приходят в общий чат
+{{end}}`,
+	ReReview: `🔁{{range .Mentions}} {{.}}{{end}} в MR появились новые коммиты, посмотрите ещё раз и поставьте 👍 заново
+{{link .URL .Title}}`,
+	DraftPending:    `📝 {{link .URL .Title}} — черновик, назначу ревьюеров, когда он будет готов`,
+	PipelinePending: `⏳ pipeline {{link .URL .Title}} ещё не прошёл, назначу ревьюеров, когда он станет зелёным`,
+	PipelineFailed: `🔴 {{.Mention}} pipeline твоего MR упал, ревьюеры ждут исправления
+{{link .URL .Title}}`,
+	ReadyToMerge: `✅ {{.Mention}} твой MR одобрен{{if .HasConflicts}}, но в нём конфликты с целевой веткой, разреши их перед merge{{else if .IsAutoMerge}} и будет влит, когда пройдёт pipeline{{else}} и готов к merge{{end}}
+{{link .URL .Title}}`,
+	StaleNudge: `💤 {{.Mention}} твой MR без движения {{.Days}} рабочих дн. или треды ждут твоего ответа. Ревьюерам о нём не напоминаю, пока не будет коммитов или ответов{{if .CloseDays}}, через {{.CloseDays}} дн. он будет закрыт{{end}}
+{{link .URL .Title}}`,
+	EscalationReminder: `⏰ {{.Mention}} ревью ждёт тебя уже {{.Hours}}ч
+{{link .URL .Title}}`,
+	EscalationLead: `🚨{{range .Leads}} {{.}}{{end}} ревью {{.Mention}} висит уже {{.Hours}}ч
+{{link .URL .Title}}`,
+	EscalationReallocate: `♻️ ревью {{.Mention}} висит уже {{.Hours}}ч, ищу другого ревьюера
+{{link .URL .Title}}`,
+	RegisterVerify: `Чтобы подтвердить, что аккаунт GitLab {{esc .GitlabName}} твой, {{if .IssueURL}}оставь комментарий с кодом <code>{{esc .Code}}</code> в {{link .IssueURL ""}}{{else}}поставь в статус GitLab код <code>{{esc .Code}}</code>{{end}} и отправь /verify в течение {{.Minutes}} минут`,
+	Users: `Участники:
+{{table .Rows}}`,
+	Whois: `{{.Mention}}{{if .IsAdmin}} (админ){{end}}
+GitLab: {{esc .GitlabName}} ({{.GitlabID}})
+Роль: {{.Role}}
+Статус: {{if .IsActive}}активен{{else}}неактивен{{end}}
+Открытых ревью: {{.Reviews}}`,
+	Stats: `Открытые ревью:
+{{table .Rows}}`,
+	Queue: `Очередь ревью:
+{{range .}}{{.Emoji}} {{link .URL .Title}}{{range $i, $u := .Waiting}}{{if $i}},{{else}} —{{end}} {{esc $u}}{{end}}
+{{else}}пусто 🎉
+{{end}}`,
+
+	CommandPrefix + "help":       `показать эту справку`,
+	CommandPrefix + "register":   `зарегистрироваться в боте`,
+	CommandPrefix + "verify":     `завершить регистрацию после публикации кода в gitlab`,
+	CommandPrefix + "mr":         `назначить ревьюеров на merge request`,
+	CommandPrefix + "reassign":   `передать ревью другому, лучшему кандидату, если @to не указан`,
+	CommandPrefix + "swap":       `передать своё ревью другому`,
+	CommandPrefix + "inactive":   `покинуть проект, ревью переназначаются`,
+	CommandPrefix + "active":     `вернуться в проект`,
+	CommandPrefix + "stats":      `показать открытые ревью участников`,
+	CommandPrefix + "queue":      `показать открытые merge request и ревьюеров, которых они ждут`,
+	CommandPrefix + "users":      `список зарегистрированных участников`,
+	CommandPrefix + "whois":      `показать аккаунт gitlab, роль и ревью участника`,
+	CommandPrefix + "role":       `изменить роль участника`,
+	CommandPrefix + "unregister": `удалить участника из бота, ревью переназначаются`,
+	CommandPrefix + "settings":   `выбрать, куда присылать напоминания`,
+	CommandPrefix + "start":      `получать напоминания в этом чате`,
+	CommandPrefix + "daily":      `разослать ежедневные напоминания сейчас`,
+
+	ErrNotRegistered:         `{{if .}}@{{esc .}} не зарегистрирован{{else}}вы ещё не зарегистрированы, используйте /register в общем чате{{end}}`,
+	ErrNotReviewer:           `{{if .}}@{{esc .}} не ревьюер{{else}}вы не ревьюер{{end}} этого merge request`,
+	ErrNoReviewers:           `{{if .}}@{{esc .}}: {{end}}не найдены участники для ревью`,
+	ErrReviewApproved:        `ревью уже одобрено`,
+	ErrUserInactive:          `@{{esc .}} неактивен`,
+	ErrUserIsAuthor:          `@{{esc .}} автор merge request`,
+	ErrAlreadyReviewer:       `@{{esc .}} уже ревьюит этот merge request`,
+	ErrInvalidArgument:       `неверный аргумент {{esc .}}`,
+	ErrInvalidRole:           `роль должна быть одной из {{esc .}}`,
+	ErrInvalidNotifyMode:     `режим уведомлений должен быть одним из {{esc .}}`,
+	ErrInvalidQuietHours:     `неверные тихие часы {{esc .}}, ожидается диапазон часов вида 22-8 или off`,
+	ErrInvalidMR:             `неверный merge request {{esc .}}`,
+	ErrMrNotOnReview:         `merge request {{esc .}} не на ревью`,
+	ErrMrClosed:              `merge request уже закрыт`,
+	ErrMrTitle:               `в заголовке mr должен быть номер задачи в квадратных скобках без пробелов. Пример: [NC-1234]`,
+	ErrGetUsers:              `не удалось получить участников`,
+	ErrAdminOnly:             `доступ запрещён: команда доступна только админам`,
+	ErrChangeOtherUser:       `доступ запрещён: изменять других участников могут только админы`,
+	ErrRegisterPrivRole:      `доступ запрещён: только админы могут регистрировать с ролью lead`,
+	ErrRegisterUsage:         `команде нужен id или имя в gitlab и, при желании, роль. Подробнее: /help`,
+	ErrMrUsage:               `команде нужна ссылка на merge request. Подробнее: /help`,
+	ErrOverrideUsage:         `ревьюеров указывают как @username, -@username чтобы исключить, +dev или +lead для дополнительного места`,
+	ErrWhoisUsage:            `использование: /whois username`,
+	ErrRoleUsage:             `использование: /role username dev|lead`,
+	ErrSettingsUsage:         `использование: /settings [notify group|dm|both] [quiet from-to|off]. Подробнее: /help`,
+	ErrReassignUsage:         `команде нужна ссылка или id merge request. Подробнее: /help`,
+	ErrNoPendingRegistration: `нет регистрации для подтверждения, сначала используйте /register`,
+	ErrCodeExpired:           `код подтверждения истёк, используйте /register снова`,
+	ErrCodeNotFound:          `код подтверждения пока не найден в gitlab, попробуйте /verify чуть позже`,
+	ErrFeatureUnavailable:    `эта функция недоступна`,
+	ErrCommandFailed:         `команда /{{.}} завершилась с ошибкой`,
+	ErrReviewsNotMoved:       `ревью MR {{esc .}} не переданы другим участникам, используйте /reassign`,
+	ErrUnregisterReviews:     `участник не удалён: ревью MR {{esc .}} не переданы другим участникам, сначала используйте /reassign`,
+}
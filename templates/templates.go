@@ -0,0 +1,250 @@
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	tg "tgj-bot/external_service/telegram"
+)
+
+const (
+	English = "en"
+	Russian = "ru"
+
+	ext = ".tmpl"
+)
+
+// template names
+const (
+	Help                 = "help"
+	Success              = "success"
+	MrStatus             = "mr_status"
+	MrParty              = "mr_party"
+	NewReview            = "new_review"
+	DailyGreeting        = "daily_greeting"
+	DailyUser            = "daily_user"
+	DailyDirect          = "daily_direct"
+	Praise               = "praise"
+	Motivate             = "motivate"
+	MoveTaskToQA         = "move_task_to_qa"
+	ButtonTake           = "button_take"
+	ButtonReassign       = "button_reassign"
+	ButtonSnooze         = "button_snooze"
+	ButtonOpen           = "button_open"
+	AnswerTake           = "answer_take"
+	AnswerSnooze         = "answer_snooze"
+	StartDone            = "start_done"
+	Settings             = "settings"
+	EscalationReminder   = "escalation_reminder"
+	EscalationLead       = "escalation_lead"
+	EscalationReallocate = "escalation_reallocate"
+	Stats                = "stats"
+	RegisterVerify       = "register_verify"
+	Users                = "users"
+	Whois                = "whois"
+	ReReview             = "re_review"
+	DraftPending         = "draft_pending"
+	PipelinePending      = "pipeline_pending"
+	PipelineFailed       = "pipeline_failed"
+	Queue                = "queue"
+	ReadyToMerge         = "ready_to_merge"
+	StaleNudge           = "stale_nudge"
+)
+
+// CommandPrefix names description of bot command in help and menu, e.g. cmd_help
+const CommandPrefix = "cmd_"
+
+// errors shown to users, data is a string argument if template uses it
+const (
+	ErrNotRegistered         = "err_not_registered"
+	ErrNotReviewer           = "err_not_reviewer"
+	ErrNoReviewers           = "err_no_reviewers"
+	ErrReviewApproved        = "err_review_approved"
+	ErrUserInactive          = "err_user_inactive"
+	ErrUserIsAuthor          = "err_user_is_author"
+	ErrAlreadyReviewer       = "err_already_reviewer"
+	ErrInvalidArgument       = "err_invalid_argument"
+	ErrInvalidRole           = "err_invalid_role"
+	ErrInvalidNotifyMode     = "err_invalid_notify_mode"
+	ErrInvalidQuietHours     = "err_invalid_quiet_hours"
+	ErrInvalidMR             = "err_invalid_mr"
+	ErrMrNotOnReview         = "err_mr_not_on_review"
+	ErrMrClosed              = "err_mr_closed"
+	ErrMrTitle               = "err_mr_title"
+	ErrGetUsers              = "err_get_users"
+	ErrAdminOnly             = "err_admin_only"
+	ErrChangeOtherUser       = "err_change_other_user"
+	ErrRegisterPrivRole      = "err_register_priv_role"
+	ErrRegisterUsage         = "err_register_usage"
+	ErrMrUsage               = "err_mr_usage"
+	ErrOverrideUsage         = "err_override_usage"
+	ErrWhoisUsage            = "err_whois_usage"
+	ErrRoleUsage             = "err_role_usage"
+	ErrSettingsUsage         = "err_settings_usage"
+	ErrReassignUsage         = "err_reassign_usage"
+	ErrNoPendingRegistration = "err_no_pending_registration"
+	ErrCodeExpired           = "err_code_expired"
+	ErrCodeNotFound          = "err_code_not_found"
+	ErrFeatureUnavailable    = "err_feature_unavailable"
+	ErrCommandFailed         = "err_command_failed"
+	ErrReviewsNotMoved       = "err_reviews_not_moved"
+	ErrUnregisterReviews     = "err_unregister_reviews"
+)
+
+type Config struct {
+	// directory with overrides, one subdirectory per language: <dir>/<lang>/<name>.tmpl
+	Dir         string `json:"dir"`
+	DefaultLang string `json:"default_lang"`
+	// language by chat id
+	Chats map[int64]string `json:"chats"`
+}
+
+type Templates struct {
+	defaultLang string
+	chats       map[int64]string
+	sets        map[string]*template.Template
+}
+
+// messages are sent as HTML, so user data is put into templates with these helpers:
+// {{esc .Username}}, {{link .URL .Title}}, {{bold .Text}}, {{pre .Text}}, {{table .Rows}}
+var funcs = template.FuncMap{
+	"esc":   tg.Escape,
+	"link":  tg.Link,
+	"bold":  tg.Bold,
+	"pre":   tg.Pre,
+	"table": tg.Table,
+}
+
+var defaults = map[string]map[string]string{
+	English: en,
+	Russian: ru,
+}
+
+func New(cfg Config) (*Templates, error) {
+	t := &Templates{
+		defaultLang: cfg.DefaultLang,
+		chats:       cfg.Chats,
+		sets:        make(map[string]*template.Template),
+	}
+	if t.defaultLang == "" {
+		t.defaultLang = English
+	}
+
+	// english goes first, other languages fall back to it
+	if err := t.parse(English, defaults[English]); err != nil {
+		return nil, err
+	}
+	for lang, set := range defaults {
+		if lang == English {
+			continue
+		}
+		if err := t.parse(lang, set); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Dir != "" {
+		if err := t.loadDir(cfg.Dir); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, ok := t.sets[t.defaultLang]; !ok {
+		return nil, fmt.Errorf("templates for default language %s not found", t.defaultLang)
+	}
+	return t, nil
+}
+
+// Lang returns language of the chat
+func (t *Templates) Lang(chatID int64) string {
+	if lang, ok := t.chats[chatID]; ok {
+		if _, ok := t.sets[lang]; ok {
+			return lang
+		}
+	}
+	return t.defaultLang
+}
+
+// Render renders template in language of the chat, english template is used if it fails,
+// e.g. broken override. Empty string is returned only if english one fails too
+func (t *Templates) Render(chatID int64, name string, data interface{}) string {
+	lang := t.Lang(chatID)
+	var buf bytes.Buffer
+	err := t.sets[lang].ExecuteTemplate(&buf, name, data)
+	if err != nil && lang != English {
+		log.Printf("render template %s/%s: %v, english is used", lang, name, err)
+		buf.Reset()
+		err = t.sets[English].ExecuteTemplate(&buf, name, data)
+	}
+	if err != nil {
+		log.Printf("render template %s: %v", name, err)
+		return ""
+	}
+	return buf.String()
+}
+
+// Variants returns non empty lines of the template, e.g. to pick a random phrase
+func (t *Templates) Variants(chatID int64, name string) (vs []string) {
+	for _, line := range strings.Split(t.Render(chatID, name, nil), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			vs = append(vs, line)
+		}
+	}
+	return
+}
+
+func (t *Templates) parse(lang string, set map[string]string) error {
+	tmpl, ok := t.sets[lang]
+	if !ok {
+		if base, ok := t.sets[English]; ok {
+			var err error
+			if tmpl, err = base.Clone(); err != nil {
+				return err
+			}
+		} else {
+			tmpl = template.New(lang).Funcs(funcs)
+		}
+		t.sets[lang] = tmpl
+	}
+
+	for name, text := range set {
+		if _, err := tmpl.New(name).Parse(text); err != nil {
+			return fmt.Errorf("parse template %s/%s: %v", lang, name, err)
+		}
+	}
+	return nil
+}
+
+func (t *Templates) loadDir(dir string) error {
+	langs, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("read templates dir: %v", err)
+	}
+	for _, lang := range langs {
+		if !lang.IsDir() {
+			continue
+		}
+		files, err := filepath.Glob(filepath.Join(dir, lang.Name(), "*"+ext))
+		if err != nil {
+			return err
+		}
+
+		set := make(map[string]string, len(files))
+		for _, file := range files {
+			text, err := ioutil.ReadFile(file)
+			if err != nil {
+				return fmt.Errorf("read template: %v", err)
+			}
+			set[strings.TrimSuffix(filepath.Base(file), ext)] = string(text)
+		}
+		if err := t.parse(lang.Name(), set); err != nil {
+			return err
+		}
+		log.Printf("Templates %s loaded from %s: %d", lang.Name(), dir, len(set))
+	}
+	return nil
+}
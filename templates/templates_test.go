@@ -0,0 +1,101 @@
+package templates
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDefaultsAreComplete(t *testing.T) {
+	for lang, set := range defaults {
+		for name := range en {
+			if _, ok := set[name]; !ok {
+				t.Fatalf("template %s not found in %s", name, lang)
+			}
+		}
+	}
+}
+
+func TestTemplates_Render(t *testing.T) {
+	tmpl, err := New(Config{
+		DefaultLang: English,
+		Chats:       map[int64]string{1: Russian, 2: "unknown"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := struct{ Mention, URL, Title string }{"@alice&lt;", "url", "!1 Fix"}
+	if value := tmpl.Render(0, NewReview, data); value != "New review:\n@alice&lt;\n-----------------------\n<a href=\"url\">!1 Fix</a>" {
+		t.Fatalf("unexpected english text: %q", value)
+	}
+	if value := tmpl.Render(1, NewReview, data); value != "Новое ревью:\n@alice&lt;\n-----------------------\n<a href=\"url\">!1 Fix</a>" {
+		t.Fatalf("unexpected russian text: %q", value)
+	}
+	if tmpl.Lang(2) != English {
+		t.Fatal("unknown language must fall back to default")
+	}
+	if len(tmpl.Variants(0, Praise)) != 5 {
+		t.Fatal("unexpected number of variants")
+	}
+}
+
+func TestTemplates_LoadDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for lang, text := range map[string]string{English: "Yay!", "uk": "Готово!"} {
+		if err := os.Mkdir(filepath.Join(dir, lang), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, lang, Success+ext), []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tmpl, err := New(Config{Dir: dir, Chats: map[int64]string{1: "uk"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value := tmpl.Render(0, Success, nil); value != "Yay!" {
+		t.Fatalf("template is not overridden: %q", value)
+	}
+	if value := tmpl.Render(1, Success, nil); value != "Готово!" {
+		t.Fatalf("template of new language is not loaded: %q", value)
+	}
+	// missing templates of new language fall back to english
+	if value := tmpl.Render(1, ButtonOpen, nil); value != "Open MR" {
+		t.Fatalf("unexpected fallback text: %q", value)
+	}
+}
+
+func TestTemplates_RenderFallback(t *testing.T) {
+	dir, err := ioutil.TempDir("", "templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// override fails on execution, the field does not exist
+	if err := os.Mkdir(filepath.Join(dir, Russian), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, Russian, ErrInvalidMR+ext), []byte("{{.Missing}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl, err := New(Config{Dir: dir, Chats: map[int64]string{1: Russian}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value := tmpl.Render(1, ErrInvalidMR, "42"); value != "invalid merge request 42" {
+		t.Fatalf("broken template must fall back to english: %q", value)
+	}
+	if value := tmpl.Render(1, "unknown", nil); value != "" {
+		t.Fatalf("unknown template must be empty: %q", value)
+	}
+}